@@ -16,8 +16,12 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
 	"github.com/pluggedin/mcp-analytics/internal/api"
+	"github.com/pluggedin/mcp-analytics/internal/cache"
 	"github.com/pluggedin/mcp-analytics/internal/config"
+	"github.com/pluggedin/mcp-analytics/internal/database"
+	"github.com/pluggedin/mcp-analytics/internal/realtime"
 	"github.com/pluggedin/mcp-analytics/internal/search"
 )
 
@@ -43,6 +47,30 @@ func main() {
 		log.Fatalf("Failed to initialize search service: %v", err)
 	}
 
+	// Initialize cache
+	log.Println("Initializing cache...")
+	cacheService, err := cache.New(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize cache: %v", err)
+	}
+	defer cacheService.Close()
+
+	// Initialize PostgreSQL (runs pending migrations)
+	log.Println("Initializing PostgreSQL...")
+	db, err := database.NewPostgres(cfg.PostgresURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize PostgreSQL: %v", err)
+	}
+	defer db.Close()
+
+	// Initialize MongoDB
+	log.Println("Initializing MongoDB...")
+	mongoDB, err := database.NewMongo(cfg.MongoDBURL, cfg.MongoDBDatabase)
+	if err != nil {
+		log.Fatalf("Failed to initialize MongoDB: %v", err)
+	}
+	defer mongoDB.Client().Disconnect(context.Background())
+
 	// Run migration to populate source fields for existing servers
 	log.Println("Running server source migration...")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -53,13 +81,123 @@ func main() {
 	}
 	cancel()
 
+	// Realtime updates are published to the hub, which is nil when disabled,
+	// and relayed to the other replicas through Redis
+	var hub *realtime.Hub
+	var broadcaster *realtime.Broadcaster
+	if cfg.EnableWebSocket || cfg.EnableSSE {
+		hub = realtime.NewHub(cfg.RealtimeMaxConnections, cfg.RealtimeClientBuffer, cfg.RealtimeReplayBuffer)
+		broadcaster, err = realtime.NewBroadcaster(cacheService.Client(), hub, cfg.InstanceID)
+		if err != nil {
+			log.Fatalf("Failed to initialize realtime broadcaster: %v", err)
+		}
+	}
+
+	// Create stores
+	featuredStore := analytics.NewFeaturedStore(db)
+	installStore := analytics.NewInstallStore(db)
+	ratingStore := analytics.NewRatingStore(db)
+	metricsRollup, err := analytics.NewMetricsRollup(mongoDB, installStore, 2*time.Duration(cfg.EventFlushInterval)*time.Second+time.Minute)
+	if err != nil {
+		log.Fatalf("Failed to initialize metrics rollup: %v", err)
+	}
+	indexSyncer := analytics.NewIndexSyncer(installStore, ratingStore, metricsRollup, searchService, hub, cfg.MinRatingCount)
+	reviewModerator := analytics.NewReviewModerator(db, indexSyncer)
+	fraudDetector := analytics.NewFraudDetector(db, mongoDB, indexSyncer)
+	usageBuffer, err := analytics.NewUsageBuffer(mongoDB, cfg.EventBatchSize, time.Duration(cfg.EventFlushInterval)*time.Second)
+	if err != nil {
+		log.Fatalf("Failed to initialize usage buffer: %v", err)
+	}
+	searchAnalytics, err := analytics.NewSearchAnalytics(mongoDB, cacheService.Client(), cfg.EventBatchSize, time.Duration(cfg.EventFlushInterval)*time.Second)
+	if err != nil {
+		log.Fatalf("Failed to initialize search analytics: %v", err)
+	}
+	funnelStore := analytics.NewFunnelStore(db, searchAnalytics)
+	experimentStore, err := analytics.NewExperimentStore(db, searchAnalytics, cfg.EventBatchSize, time.Duration(cfg.EventFlushInterval)*time.Second)
+	if err != nil {
+		log.Fatalf("Failed to initialize experiments: %v", err)
+	}
+
+	webhookStore := analytics.NewWebhookStore(db, cfg.IsDevelopment())
+	anomalyDetector, err := analytics.NewAnomalyDetector(mongoDB, metricsRollup, webhookStore)
+	if err != nil {
+		log.Fatalf("Failed to initialize anomaly detector: %v", err)
+	}
+
+	trendingScorer, err := analytics.NewTrendingScorer(mongoDB, searchService, hub, cfg.TrendingPeriodHours, cfg.TrendingMinInstalls, cfg.PopularityDecayRate)
+	if err != nil {
+		log.Fatalf("Failed to initialize trending scorer: %v", err)
+	}
+	activeUsers, err := analytics.NewActiveUsers(cacheService.Client(), mongoDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize active user tracking: %v", err)
+	}
+	qualityScorer, err := analytics.NewQualityScorer(mongoDB, installStore, searchService, cfg.MinRatingCount)
+	if err != nil {
+		log.Fatalf("Failed to initialize quality scorer: %v", err)
+	}
+	coInstallModel, err := analytics.NewCoInstallModel(db, mongoDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize co-install model: %v", err)
+	}
+	personalizer := analytics.NewPersonalizer(installStore, coInstallModel, searchService)
+
+	platformStats := analytics.NewPlatformStats(searchService, installStore, ratingStore, activeUsers, metricsRollup)
+
 	// Create event handler
-	eventHandler := api.NewEventHandler(searchService)
+	eventHandler := api.NewEventHandler(searchService, indexSyncer, hub)
+
+	// Create search handler
+	searchHandler := api.NewSearchHandler(searchService, searchAnalytics, personalizer, experimentStore, cfg)
+	funnelHandler := api.NewFunnelHandler(funnelStore, searchService)
+
+	// Create discovery handlers
+	discoveryHandler := api.NewDiscoveryHandler(searchService, featuredStore, experimentStore, cacheService, cfg)
+	featuredHandler := api.NewFeaturedHandler(featuredStore, searchService, cacheService)
+
+	// Create user interaction handlers
+	installHandler := api.NewInstallHandler(installStore, indexSyncer, activeUsers, searchService)
+	ratingHandler := api.NewRatingHandler(ratingStore, indexSyncer, searchService)
+	moderationHandler := api.NewModerationHandler(reviewModerator)
+	fraudHandler := api.NewFraudHandler(fraudDetector)
+	experimentHandler := api.NewExperimentHandler(experimentStore)
+	usageHandler := api.NewUsageHandler(usageBuffer, activeUsers, searchService)
+	metricsHandler := api.NewMetricsHandler(metricsRollup, activeUsers, searchService)
+	qualityHandler := api.NewQualityHandler(qualityScorer)
+	alertHandler := api.NewAlertHandler(anomalyDetector, webhookStore, searchService, cfg)
+	statsHandler := api.NewStatsHandler(platformStats, cacheService, cfg)
+	rankHandler := api.NewRankHandler(trendingScorer, searchService, cacheService, cfg)
+	recommendationHandler := api.NewRecommendationHandler(coInstallModel, installStore, searchService, cacheService, cfg)
+	analyticsHandler := api.NewAnalyticsHandler(installStore, ratingStore, indexSyncer, activeUsers, metricsRollup, trendingScorer, searchService, cacheService, cfg)
+
+	// Schedule background jobs
+	scheduler := analytics.NewScheduler()
+	scheduler.Every("archive-featured", time.Minute, featuredHandler.ArchiveExpired)
+	scheduler.Every("sync-index-stats", time.Duration(cfg.EventFlushInterval)*time.Second, indexSyncer.Flush)
+	scheduler.Every("moderate-reviews", time.Minute, reviewModerator.RunHeuristics)
+	scheduler.Every("detect-fraud", 15*time.Minute, fraudDetector.Run)
+	scheduler.Every("rollup-metrics", time.Minute, metricsRollup.Run)
+	scheduler.Every("sync-usage-stats", time.Minute, indexSyncer.SyncUsage)
+	scheduler.Every("detect-anomalies", 5*time.Minute, anomalyDetector.Run)
+	scheduler.Every("score-trending", 15*time.Minute, trendingScorer.Run)
+	scheduler.Every("score-quality", time.Hour, qualityScorer.Run)
+	scheduler.Every("build-co-installs", time.Hour, coInstallModel.Run)
+	scheduler.Every("load-experiments", 30*time.Second, experimentStore.Refresh)
+	scheduler.Every("refresh-rating-weights", 15*time.Minute, indexSyncer.RefreshRatingWeights)
+	scheduler.Every("persist-active-users", time.Hour, activeUsers.Persist)
+	scheduler.Every("refresh-stats", time.Duration(cfg.StatsCacheTTL)*time.Second/2, statsHandler.Refresh)
+	scheduler.Start()
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:           "MCP Analytics Service",
 		EnablePrintRoutes: cfg.Environment == "development",
+
+		// Read client IPs from X-Forwarded-For only when set by a trusted proxy
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.GetTrustedProxies(),
+		EnableIPValidation:      true,
 	})
 
 	// Global middleware
@@ -69,7 +207,7 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     strings.Join(cfg.GetCORSOrigins(), ","),
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-API-Key,X-Internal-Key",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-API-Key,X-Internal-Key,X-Session-ID",
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -98,49 +236,81 @@ func main() {
 	// Public API routes
 	v1 := app.Group("/v1")
 
-	// Search endpoint
-	v1.Get("/search", func(c *fiber.Ctx) error {
-		query := search.SearchQuery{
-			Query:   c.Query("q"),
-			Sort:    c.Query("sort", "relevance"),
-			Offset:  c.QueryInt("offset", 0),
-			Limit:   c.QueryInt("limit", 20),
-			Filters: make(map[string]interface{}),
-		}
+	// Search endpoints (user token optional)
+	optionalUserAuth := api.OptionalUserAuthMiddleware(cfg.JWTSecret)
+	v1.Get("/search", optionalUserAuth, searchHandler.Search)
+	v1.Post("/search/events", optionalUserAuth, searchHandler.TrackEvent)
+	v1.Post("/views", optionalUserAuth, funnelHandler.TrackView)
 
-		// Add filters
-		if pkgType := c.Query("package_type"); pkgType != "" {
-			query.Filters["package_type"] = pkgType
-		}
-		if transport := c.Query("transport"); transport != "" {
-			query.Filters["transport"] = transport
-		}
-		if category := c.Query("category"); category != "" {
-			query.Filters["categories"] = category
-		}
-		if source := c.Query("source"); source != "" {
-			query.Filters["source"] = source
-		}
+	// Discovery endpoints (user token optional, for experiment assignment)
+	v1.Get("/trending", optionalUserAuth, discoveryHandler.Trending)
+	v1.Get("/trending/movers", rankHandler.Movers)
+	v1.Get("/top-rated", optionalUserAuth, discoveryHandler.TopRated)
+	v1.Get("/recent", optionalUserAuth, discoveryHandler.Recent)
+	v1.Get("/featured", discoveryHandler.Featured)
 
-		// Validate limit
-		if query.Limit > cfg.SearchMaxResults {
-			query.Limit = cfg.SearchMaxResults
-		}
+	// Platform statistics
+	v1.Get("/stats/global", statsHandler.Global)
+	v1.Get("/stats/categories", statsHandler.Categories)
+	v1.Get("/stats/tools", statsHandler.Tools)
 
-		// Execute search
-		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
-		defer cancel()
+	// User interaction endpoints (require a user token)
+	userAuth := api.UserAuthMiddleware(cfg.JWTSecret)
+	v1.Post("/installs", userAuth, installHandler.Install)
+	v1.Post("/uninstalls", userAuth, installHandler.Uninstall)
+	v1.Post("/ratings", userAuth, ratingHandler.Submit)
+	v1.Post("/reviews/:id/vote", userAuth, ratingHandler.Vote)
+	v1.Post("/reviews/:id/flag", userAuth, moderationHandler.Flag)
+	v1.Post("/usage", userAuth, usageHandler.Track)
+	v1.Get("/recommendations", userAuth, recommendationHandler.Recommendations)
+	v1.Post("/servers/:id/webhooks", userAuth, alertHandler.CreateWebhook)
+	v1.Get("/webhooks", userAuth, alertHandler.Webhooks)
+	v1.Delete("/webhooks/:id", userAuth, alertHandler.DeleteWebhook)
 
-		result, err := searchService.Search(ctx, query)
-		if err != nil {
-			log.Printf("Search error: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Search failed",
-			})
-		}
+	// Server endpoints (IDs are URL-encoded)
+	v1.Get("/servers/:id/reviews", ratingHandler.Reviews)
+	v1.Get("/servers/:id/metrics", metricsHandler.ServerMetrics)
+	v1.Get("/servers/:id/quality", qualityHandler.ServerQuality)
+	v1.Get("/servers/:id/active-users", metricsHandler.ActiveUsers)
+	v1.Get("/servers/:id/analytics", analyticsHandler.ServerAnalytics)
+	v1.Get("/servers/:id/funnel", funnelHandler.ServerFunnel)
+	v1.Get("/servers/:id/alerts", alertHandler.ServerAlerts)
+	v1.Get("/servers/:id/rank-history", rankHandler.RankHistory)
+	v1.Get("/servers/:id/also-installed", recommendationHandler.AlsoInstalled)
 
-		return c.JSON(result)
-	})
+	// Admin API routes (protected by user token with admin role)
+	admin := v1.Group("/admin", userAuth, api.AdminAuthMiddleware())
+	admin.Get("/featured", featuredHandler.List)
+	admin.Post("/featured", featuredHandler.Create)
+	admin.Get("/featured/:id", featuredHandler.Get)
+	admin.Put("/featured/:id", featuredHandler.Update)
+	admin.Delete("/featured/:id", featuredHandler.Delete)
+	admin.Get("/reviews", moderationHandler.Queue)
+	admin.Get("/reviews/:id/flags", moderationHandler.Flags)
+	admin.Post("/reviews/:id/approve", moderationHandler.Approve)
+	admin.Post("/reviews/:id/reject", moderationHandler.Reject)
+	admin.Get("/alerts", alertHandler.Alerts)
+	admin.Get("/fraud", fraudHandler.List)
+	admin.Post("/fraud/:id/dismiss", fraudHandler.Dismiss)
+	admin.Get("/search/top-queries", searchHandler.TopQueries)
+	admin.Get("/search/zero-results", searchHandler.ZeroResultQueries)
+	admin.Get("/search/ctr", searchHandler.ClickThrough)
+	admin.Get("/funnel", funnelHandler.PlatformFunnel)
+	admin.Get("/experiments", experimentHandler.List)
+	admin.Post("/experiments", experimentHandler.Create)
+	admin.Get("/experiments/:id", experimentHandler.Get)
+	admin.Post("/experiments/:id/start", experimentHandler.Start)
+	admin.Post("/experiments/:id/stop", experimentHandler.Stop)
+	admin.Get("/experiments/:id/report", experimentHandler.Report)
+
+	// Realtime updates
+	realtimeHandler := api.NewRealtimeHandler(hub)
+	if cfg.EnableWebSocket {
+		v1.Get("/realtime", realtimeHandler.Upgrade, realtimeHandler.Connect())
+	}
+	if cfg.EnableSSE {
+		v1.Get("/events/stream", realtimeHandler.Stream)
+	}
 
 	// Start server in goroutine
 	go func() {
@@ -158,10 +328,39 @@ func main() {
 
 	log.Println("Shutting down server...")
 
+	// Disconnect realtime clients, whose connections would hold up the drain
+	if broadcaster != nil {
+		if err := broadcaster.Close(); err != nil {
+			log.Printf("Realtime broadcaster close warning: %v", err)
+		}
+	}
+	hub.Close()
+
 	// Graceful shutdown with timeout
 	if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Stop background jobs, then flush pending stats and buffered events,
+	// including those of requests drained above
+	scheduler.Stop()
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := indexSyncer.Flush(flushCtx); err != nil {
+		log.Printf("Final stats flush warning: %v", err)
+	}
+	if err := usageBuffer.Close(flushCtx); err != nil {
+		log.Printf("Final usage flush warning: %v", err)
+	}
+	if err := searchAnalytics.Close(flushCtx); err != nil {
+		log.Printf("Final search analytics flush warning: %v", err)
+	}
+	if err := experimentStore.Close(flushCtx); err != nil {
+		log.Printf("Final experiment exposure flush warning: %v", err)
+	}
+	if err := webhookStore.Close(flushCtx); err != nil {
+		log.Printf("Final webhook delivery warning: %v", err)
+	}
+	flushCancel()
+
 	log.Println("Server exited")
 }
\ No newline at end of file
@@ -0,0 +1,182 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// ActiveUsersCollection holds the persisted daily active user counts
+const ActiveUsersCollection = "active_users"
+
+// Active user key layout and retention
+const (
+	activeUsersPrefix  = "au:"
+	activeUsersGlobal  = "global"
+	activeUsersServers = "servers"
+	activeUsersTTL     = 40 * 24 * time.Hour
+	activeUsersDay     = "20060102"
+)
+
+// ActiveUsers counts distinct active users with one Redis HyperLogLog per
+// server per day. Weekly and monthly counts are unions of the daily logs.
+// Daily values are persisted to MongoDB so the history outlives Redis.
+type ActiveUsers struct {
+	redis   *redis.Client
+	history *mongo.Collection
+}
+
+// NewActiveUsers creates an active user tracker
+func NewActiveUsers(client *redis.Client, db *mongo.Database) (*ActiveUsers, error) {
+	a := &ActiveUsers{
+		redis:   client,
+		history: db.Collection(ActiveUsersCollection),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := a.history.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "server_id", Value: 1}, {Key: "date", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create active users index: %w", err)
+	}
+
+	return a, nil
+}
+
+// Track records that a user was active on a server at the given time
+func (a *ActiveUsers) Track(ctx context.Context, serverID, userID string, at time.Time) error {
+	day := at.UTC().Format(activeUsersDay)
+	serverKey := activeUsersKey(serverID, day)
+	globalKey := activeUsersKey("", day)
+	serversKey := activeUsersPrefix + activeUsersServers + ":" + day
+
+	pipe := a.redis.Pipeline()
+	pipe.PFAdd(ctx, serverKey, userID)
+	pipe.PFAdd(ctx, globalKey, userID)
+	pipe.SAdd(ctx, serversKey, serverID)
+	pipe.Expire(ctx, serverKey, activeUsersTTL)
+	pipe.Expire(ctx, globalKey, activeUsersTTL)
+	pipe.Expire(ctx, serversKey, activeUsersTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to track active user: %w", err)
+	}
+
+	return nil
+}
+
+// TrackQuietly records activity, logging instead of failing so analytics
+// never blocks the request that produced it
+func (a *ActiveUsers) TrackQuietly(ctx context.Context, serverID, userID string, at time.Time) {
+	if err := a.Track(ctx, serverID, userID, at); err != nil {
+		log.Printf("Active user tracking error: %v", err)
+	}
+}
+
+// Current returns the live counts for the day containing date. An empty
+// serverID counts users across all servers.
+func (a *ActiveUsers) Current(ctx context.Context, serverID string, date time.Time) (*model.ActiveUsers, error) {
+	day := BucketStart(model.IntervalDay, date)
+
+	pipe := a.redis.Pipeline()
+	dau := pipe.PFCount(ctx, a.windowKeys(serverID, day, 1)...)
+	wau := pipe.PFCount(ctx, a.windowKeys(serverID, day, 7)...)
+	mau := pipe.PFCount(ctx, a.windowKeys(serverID, day, 30)...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to count active users: %w", err)
+	}
+
+	return &model.ActiveUsers{
+		ServerID:  serverID,
+		Date:      day,
+		DAU:       dau.Val(),
+		WAU:       wau.Val(),
+		MAU:       mau.Val(),
+		UpdatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// History returns the persisted daily counts within [from, to)
+func (a *ActiveUsers) History(ctx context.Context, serverID string, from, to time.Time) ([]model.ActiveUsers, error) {
+	cursor, err := a.history.Find(ctx, bson.M{
+		"server_id": serverID,
+		"date":      bson.M{"$gte": from, "$lt": to},
+	}, options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active users: %w", err)
+	}
+
+	history := []model.ActiveUsers{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, fmt.Errorf("failed to decode active users: %w", err)
+	}
+
+	return history, nil
+}
+
+// Persist writes today's and yesterday's counts for every active server and
+// for all servers combined. Yesterday is repeated to pick up late events.
+// Counts only ever grow within a day, so stored counts are kept when Redis
+// reports less, as it does after losing its keys.
+func (a *ActiveUsers) Persist(ctx context.Context) error {
+	today := BucketStart(model.IntervalDay, time.Now())
+
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+		servers, err := a.redis.SMembers(ctx, activeUsersPrefix+activeUsersServers+":"+day.Format(activeUsersDay)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to list active servers: %w", err)
+		}
+
+		var writes []mongo.WriteModel
+		for _, serverID := range append([]string{""}, servers...) {
+			counts, err := a.Current(ctx, serverID, day)
+			if err != nil {
+				return err
+			}
+
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"server_id": serverID, "date": day}).
+				SetUpdate(bson.M{
+					"$max": bson.M{"dau": counts.DAU, "wau": counts.WAU, "mau": counts.MAU},
+					"$set": bson.M{"updated_at": counts.UpdatedAt},
+				}).
+				SetUpsert(true))
+		}
+
+		if _, err := a.history.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to save active users: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// windowKeys returns the daily keys of the days window days ending on day
+func (a *ActiveUsers) windowKeys(serverID string, day time.Time, days int) []string {
+	keys := make([]string, days)
+	for i := range keys {
+		keys[i] = activeUsersKey(serverID, day.AddDate(0, 0, -i).Format(activeUsersDay))
+	}
+	return keys
+}
+
+// activeUsersKey returns the HyperLogLog key of a server's day, or of all
+// servers when serverID is empty
+func activeUsersKey(serverID, day string) string {
+	if serverID == "" {
+		return activeUsersPrefix + activeUsersGlobal + ":" + day
+	}
+	return activeUsersPrefix + "server:" + serverID + ":" + day
+}
@@ -0,0 +1,437 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// AlertsCollection holds usage anomaly alerts
+const AlertsCollection = "usage_alerts"
+
+// Webhook events sent for alerts
+const (
+	AlertOpenedEvent   = "alert.opened"
+	AlertResolvedEvent = "alert.resolved"
+)
+
+// Anomaly detection tuning
+const (
+	// seasonalDays same-hour samples form the call volume baseline
+	seasonalDays = 14
+	// minVolumeBaseline is the expected calls per hour below which volume
+	// drops are too noisy to report
+	minVolumeBaseline = 10
+	// volumeZThreshold flags hours this many deviations below the baseline
+	volumeZThreshold = 3
+	// rateBaselineHours preceding hours form the success rate baseline
+	rateBaselineHours = 24
+	// minRateCalls is the fewest calls in an hour whose success rate is judged
+	minRateCalls = 10
+	// rateZThreshold and minRateDrop flag a significant and material drop
+	rateZThreshold = 3
+	minRateDrop    = 0.2
+	// maxAlertHours bounds the hours evaluated per run when catching up
+	maxAlertHours = 24
+)
+
+// alertKey identifies the series an alert is raised on
+type alertKey struct {
+	serverID       string
+	kind           string
+	capabilityType string
+	capability     string
+}
+
+// AnomalyDetector raises alerts when a server's or capability's hourly call
+// volume or success rate falls far below its baseline. Volume is compared
+// with the same hour on previous days so daily seasonality does not trigger
+// alerts; success rate is compared with the preceding day.
+type AnomalyDetector struct {
+	alerts   *mongo.Collection
+	rollup   *MetricsRollup
+	webhooks *WebhookStore
+}
+
+// NewAnomalyDetector creates an anomaly detector
+func NewAnomalyDetector(db *mongo.Database, rollup *MetricsRollup, webhooks *WebhookStore) (*AnomalyDetector, error) {
+	d := &AnomalyDetector{
+		alerts:   db.Collection(AlertsCollection),
+		rollup:   rollup,
+		webhooks: webhooks,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := d.resolveDuplicates(ctx); err != nil {
+		return nil, err
+	}
+
+	if _, err := d.alerts.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "last_hour", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "last_hour", Value: -1}}},
+		// One open alert per series, so instances evaluating the same hour
+		// neither open nor announce duplicates
+		{
+			Keys: bson.D{
+				{Key: "server_id", Value: 1},
+				{Key: "kind", Value: 1},
+				{Key: "capability_type", Value: 1},
+				{Key: "capability", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": model.AlertOpen}),
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create alert indexes: %w", err)
+	}
+
+	return d, nil
+}
+
+// Run evaluates every fully rolled-up hour since the previous run. It runs
+// as a scheduled job.
+func (d *AnomalyDetector) Run(ctx context.Context) error {
+	rolled, err := d.rollup.RolledUntil(ctx)
+	if err != nil {
+		return err
+	}
+	latest := BucketStart(model.IntervalHour, rolled).Add(-time.Hour)
+
+	last, err := d.rollup.watermark(ctx, watermarkAlerts)
+	if err != nil {
+		return err
+	}
+
+	next := last.Add(time.Hour)
+	if last.IsZero() || latest.Sub(next) >= maxAlertHours*time.Hour {
+		next = latest.Add(-(maxAlertHours - 1) * time.Hour)
+	}
+
+	for hour := next; !hour.After(latest); hour = hour.Add(time.Hour) {
+		if err := d.evaluate(ctx, hour); err != nil {
+			return err
+		}
+		if err := d.rollup.setWatermark(ctx, watermarkAlerts, hour); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List returns alerts newest first, for one server or all servers, in a
+// state or any state when status is empty
+func (d *AnomalyDetector) List(ctx context.Context, serverID, status string, offset, limit int) ([]model.UsageAlert, int64, error) {
+	filter := bson.M{}
+	if serverID != "" {
+		filter["server_id"] = serverID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	total, err := d.alerts.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count alerts: %w", err)
+	}
+
+	cursor, err := d.alerts.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "last_hour", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query alerts: %w", err)
+	}
+
+	alerts := []model.UsageAlert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode alerts: %w", err)
+	}
+
+	return alerts, total, nil
+}
+
+// evaluate checks one hour of every server against its baselines, opening,
+// extending and resolving alerts
+func (d *AnomalyDetector) evaluate(ctx context.Context, hour time.Time) error {
+	hours := []time.Time{hour}
+	for i := 1; i <= rateBaselineHours; i++ {
+		hours = append(hours, hour.Add(-time.Duration(i)*time.Hour))
+	}
+	for i := 2; i <= seasonalDays; i++ {
+		hours = append(hours, hour.AddDate(0, 0, -i))
+	}
+
+	buckets, err := d.rollup.HourlyBuckets(ctx, hours)
+	if err != nil {
+		return err
+	}
+
+	// Per server and capability, the usage of each hour
+	series := make(map[alertKey]map[int64]model.UsageMetrics)
+	add := func(serverID, capabilityType, capability string, start time.Time, usage model.UsageMetrics) {
+		key := alertKey{serverID: serverID, capabilityType: capabilityType, capability: capability}
+		if series[key] == nil {
+			series[key] = make(map[int64]model.UsageMetrics)
+		}
+		series[key][start.Unix()] = usage
+	}
+	for _, bucket := range buckets {
+		add(bucket.ServerID, "", "", bucket.BucketStart, bucket.Usage)
+		for _, capability := range bucket.Capabilities {
+			add(bucket.ServerID, capability.Type, capability.Name, bucket.BucketStart, capability.UsageMetrics)
+		}
+	}
+
+	fired := make(map[alertKey]*model.UsageAlert)
+	for key, usage := range series {
+		for _, alert := range checkSeries(key, hour, usage) {
+			fired[alertKey{key.serverID, alert.Kind, key.capabilityType, key.capability}] = alert
+		}
+	}
+
+	return d.reconcile(ctx, hour, fired)
+}
+
+// checkSeries applies the volume and success rate checks to one series
+func checkSeries(key alertKey, hour time.Time, usage map[int64]model.UsageMetrics) []*model.UsageAlert {
+	var alerts []*model.UsageAlert
+	current := usage[hour.Unix()]
+
+	newAlert := func(kind, severity string, observed, expected, z float64) *model.UsageAlert {
+		return &model.UsageAlert{
+			ServerID:       key.serverID,
+			Kind:           kind,
+			Severity:       severity,
+			CapabilityType: key.capabilityType,
+			Capability:     key.capability,
+			Observed:       roundScore(observed),
+			Expected:       roundScore(expected),
+			ZScore:         roundScore(z),
+		}
+	}
+
+	// Call volume against the same hour on previous days; missing hours had no calls
+	samples := make([]float64, 0, seasonalDays)
+	for i := 1; i <= seasonalDays; i++ {
+		samples = append(samples, float64(usage[hour.AddDate(0, 0, -i).Unix()].Count))
+	}
+	mean, stddev := meanStddev(samples)
+	if mean >= minVolumeBaseline {
+		// Counts are at least Poisson-noisy
+		stddev = math.Max(stddev, math.Sqrt(mean))
+		observed := float64(current.Count)
+		z := (observed - mean) / stddev
+
+		switch {
+		case current.Count == 0:
+			alerts = append(alerts, newAlert(model.AlertVolumeZero, model.SeverityCritical, observed, mean, z))
+		case z <= -volumeZThreshold:
+			severity := model.SeverityWarning
+			if observed < mean/4 {
+				severity = model.SeverityCritical
+			}
+			alerts = append(alerts, newAlert(model.AlertVolumeDrop, severity, observed, mean, z))
+		}
+	}
+
+	// Success rate against the pooled rate of the preceding hours
+	if current.Count >= minRateCalls {
+		var calls, successes int64
+		for i := 1; i <= rateBaselineHours; i++ {
+			previous := usage[hour.Add(-time.Duration(i)*time.Hour).Unix()]
+			calls += previous.Count
+			successes += previous.SuccessCount
+		}
+
+		if calls >= minRateCalls*3 {
+			expected := float64(successes) / float64(calls)
+			observed := float64(current.SuccessCount) / float64(current.Count)
+			stderr := math.Max(math.Sqrt(expected*(1-expected)/float64(current.Count)), 0.01)
+			z := (observed - expected) / stderr
+
+			if z <= -rateZThreshold && expected-observed >= minRateDrop {
+				severity := model.SeverityWarning
+				if observed < 0.5 {
+					severity = model.SeverityCritical
+				}
+				alerts = append(alerts, newAlert(model.AlertSuccessRateDrop, severity, observed, expected, z))
+			}
+		}
+	}
+
+	return alerts
+}
+
+// reconcile opens alerts for new anomalies, extends ongoing ones and
+// resolves those that no longer fire, notifying webhooks of changes
+func (d *AnomalyDetector) reconcile(ctx context.Context, hour time.Time, fired map[alertKey]*model.UsageAlert) error {
+	cursor, err := d.alerts.Find(ctx, bson.M{"status": model.AlertOpen})
+	if err != nil {
+		return fmt.Errorf("failed to query open alerts: %w", err)
+	}
+	var open []model.UsageAlert
+	if err := cursor.All(ctx, &open); err != nil {
+		return fmt.Errorf("failed to decode open alerts: %w", err)
+	}
+
+	now := time.Now().UTC()
+	var opened, resolved int
+
+	for i := range open {
+		existing := &open[i]
+		key := alertKey{existing.ServerID, existing.Kind, existing.CapabilityType, existing.Capability}
+
+		if alert, ok := fired[key]; ok {
+			delete(fired, key)
+			severity := existing.Severity
+			if alert.Severity == model.SeverityCritical {
+				severity = model.SeverityCritical
+			}
+			if _, err := d.alerts.UpdateOne(ctx, bson.M{"_id": existing.ID, "status": model.AlertOpen}, bson.M{"$set": bson.M{
+				"severity":   severity,
+				"observed":   alert.Observed,
+				"expected":   alert.Expected,
+				"z_score":    alert.ZScore,
+				"last_hour":  hour,
+				"updated_at": now,
+			}}); err != nil {
+				return fmt.Errorf("failed to update alert: %w", err)
+			}
+			continue
+		}
+
+		existing.Status = model.AlertResolved
+		existing.ResolvedAt = &now
+		existing.UpdatedAt = now
+		result, err := d.alerts.UpdateOne(ctx, bson.M{"_id": existing.ID, "status": model.AlertOpen}, bson.M{"$set": bson.M{
+			"status":      existing.Status,
+			"resolved_at": now,
+			"updated_at":  now,
+		}})
+		if err != nil {
+			return fmt.Errorf("failed to resolve alert: %w", err)
+		}
+		if result.ModifiedCount == 0 {
+			// Another instance resolved it
+			continue
+		}
+		d.notify(ctx, AlertResolvedEvent, existing)
+		resolved++
+	}
+
+	for _, alert := range fired {
+		alert.Status = model.AlertOpen
+		alert.FirstHour = hour
+		alert.LastHour = hour
+		alert.CreatedAt = now
+		alert.UpdatedAt = now
+
+		result, err := d.alerts.InsertOne(ctx, alert)
+		if mongo.IsDuplicateKeyError(err) {
+			// Another instance opened it
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save alert: %w", err)
+		}
+		alert.ID, _ = result.InsertedID.(primitive.ObjectID)
+		d.notify(ctx, AlertOpenedEvent, alert)
+		opened++
+	}
+
+	if opened+resolved > 0 {
+		log.Printf("Usage alerts for %s: %d opened, %d resolved", hour.Format(time.RFC3339), opened, resolved)
+	}
+
+	return nil
+}
+
+// resolveDuplicates resolves all but the oldest open alert of each series,
+// left by instances that opened the same alert, so the unique index on open
+// alerts can be built
+func (d *AnomalyDetector) resolveDuplicates(ctx context.Context) error {
+	cursor, err := d.alerts.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": model.AlertOpen}}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"server_id":       "$server_id",
+				"kind":            "$kind",
+				"capability_type": "$capability_type",
+				"capability":      "$capability",
+			},
+			"ids": bson.M{"$push": "$_id"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find duplicate alerts: %w", err)
+	}
+
+	var groups []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return fmt.Errorf("failed to decode duplicate alerts: %w", err)
+	}
+
+	var duplicates []primitive.ObjectID
+	for _, group := range groups {
+		duplicates = append(duplicates, group.IDs[1:]...)
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	if _, err := d.alerts.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}}, bson.M{"$set": bson.M{
+		"status":      model.AlertResolved,
+		"resolved_at": now,
+		"updated_at":  now,
+	}}); err != nil {
+		return fmt.Errorf("failed to resolve duplicate alerts: %w", err)
+	}
+
+	log.Printf("Resolved %d duplicate usage alerts", len(duplicates))
+
+	return nil
+}
+
+// notify queues an alert event for webhooks, logging failures
+func (d *AnomalyDetector) notify(ctx context.Context, event string, alert *model.UsageAlert) {
+	if err := d.webhooks.Notify(ctx, event, alert); err != nil {
+		log.Printf("Alert webhook error: %v", err)
+	}
+}
+
+// meanStddev returns the mean and population standard deviation
+func meanStddev(values []float64) (mean, stddev float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	for _, v := range values {
+		stddev += (v - mean) * (v - mean)
+	}
+	stddev = math.Sqrt(stddev / float64(len(values)))
+
+	return mean, stddev
+}
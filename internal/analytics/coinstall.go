@@ -0,0 +1,317 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// CoInstallCollection holds the related servers of each server
+const CoInstallCollection = "co_installs"
+
+// Co-install model tuning
+const (
+	// minCoInstalls is the fewest shared users for two servers to be related
+	minCoInstalls = 3
+	// maxUserInstalls excludes users with more active installs; bulk
+	// installers say little about taste and their pairs grow quadratically
+	maxUserInstalls = 200
+	// maxRelated bounds the related servers kept per server
+	maxRelated = 50
+	// maxRecommendationSources bounds the installs a recommendation draws on
+	maxRecommendationSources = 100
+	// maxBecause bounds the installed servers cited per recommendation
+	maxBecause = 3
+)
+
+// CoInstallModel is an item-item model of which servers are installed by
+// the same users. It is rebuilt offline from active, unflagged installs and
+// normalizes co-occurrence by popularity so that widely installed servers
+// are not related to everything.
+type CoInstallModel struct {
+	db      *sql.DB
+	related *mongo.Collection
+}
+
+// NewCoInstallModel creates a co-install model
+func NewCoInstallModel(db *sql.DB, mongoDB *mongo.Database) (*CoInstallModel, error) {
+	m := &CoInstallModel{
+		db:      db,
+		related: mongoDB.Collection(CoInstallCollection),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := m.related.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "server_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create co-install index: %w", err)
+	}
+
+	return m, nil
+}
+
+// Run rebuilds the model from the current installs. It runs as a scheduled job.
+func (m *CoInstallModel) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	installs, users, err := m.serverInstalls(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `
+		WITH eligible AS (
+			SELECT user_id, server_id
+			FROM user_installs
+			WHERE uninstalled_at IS NULL AND NOT flagged
+				AND user_id IN (
+					SELECT user_id FROM user_installs
+					WHERE uninstalled_at IS NULL AND NOT flagged
+					GROUP BY user_id HAVING COUNT(*) BETWEEN 2 AND $1
+				)
+		)
+		SELECT a.server_id, b.server_id, COUNT(*)
+		FROM eligible a
+		JOIN eligible b ON b.user_id = a.user_id AND b.server_id > a.server_id
+		GROUP BY a.server_id, b.server_id
+		HAVING COUNT(*) >= $2`,
+		maxUserInstalls, minCoInstalls,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query co-installs: %w", err)
+	}
+	defer rows.Close()
+
+	related := make(map[string][]model.RelatedServer)
+	var pairs int
+	for rows.Next() {
+		var a, b string
+		var count int64
+		if err := rows.Scan(&a, &b, &count); err != nil {
+			return fmt.Errorf("failed to scan co-install: %w", err)
+		}
+
+		na, nb := installs[a], installs[b]
+		jaccard := roundScore(float64(count) / float64(na+nb-count))
+		lift := roundScore(float64(count) * float64(users) / (float64(na) * float64(nb)))
+
+		related[a] = append(related[a], model.RelatedServer{ServerID: b, CoInstalls: count, Jaccard: jaccard, Lift: lift})
+		related[b] = append(related[b], model.RelatedServer{ServerID: a, CoInstalls: count, Jaccard: jaccard, Lift: lift})
+		pairs++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query co-installs: %w", err)
+	}
+
+	if err := m.save(ctx, installs, related, now); err != nil {
+		return err
+	}
+
+	log.Printf("Co-install model rebuilt: %d servers, %d pairs", len(related), pairs)
+	return nil
+}
+
+// AlsoInstalled returns the servers most often installed alongside a server,
+// strongest first. Servers without related servers get an empty list.
+func (m *CoInstallModel) AlsoInstalled(ctx context.Context, serverID string) (*model.CoInstalls, error) {
+	var coInstalls model.CoInstalls
+	err := m.related.FindOne(ctx, bson.M{"server_id": serverID}).Decode(&coInstalls)
+	if err == mongo.ErrNoDocuments {
+		return &model.CoInstalls{ServerID: serverID, Related: []model.RelatedServer{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get co-installs: %w", err)
+	}
+
+	return &coInstalls, nil
+}
+
+// Recommend suggests servers for a user with the given installs, most
+// recent first. A candidate scores the sum of its Jaccard similarity to each
+// installed server; installed servers are never suggested.
+func (m *CoInstallModel) Recommend(ctx context.Context, installed []string, limit int) ([]model.Recommendation, error) {
+	recommendations := []model.Recommendation{}
+	if len(installed) == 0 {
+		return recommendations, nil
+	}
+	if len(installed) > maxRecommendationSources {
+		installed = installed[:maxRecommendationSources]
+	}
+
+	similar, err := m.Similarities(ctx, installed)
+	if err != nil {
+		return nil, err
+	}
+
+	for candidate, sources := range similar {
+		recommendation := model.Recommendation{ServerID: candidate}
+		for _, source := range sources {
+			recommendation.Score += source.Jaccard
+		}
+		recommendation.Score = roundScore(recommendation.Score)
+
+		sort.Slice(sources, func(i, j int) bool {
+			if sources[i].Jaccard != sources[j].Jaccard {
+				return sources[i].Jaccard > sources[j].Jaccard
+			}
+			return sources[i].ServerID < sources[j].ServerID
+		})
+		for i := 0; i < len(sources) && i < maxBecause; i++ {
+			recommendation.Because = append(recommendation.Because, sources[i].ServerID)
+		}
+
+		recommendations = append(recommendations, recommendation)
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.ServerID < b.ServerID
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations, nil
+}
+
+// Similarities maps every server related to the given servers, other than
+// the servers themselves, to its similarity with each of them. ServerID of
+// each entry is the given server it is related to.
+func (m *CoInstallModel) Similarities(ctx context.Context, serverIDs []string) (map[string][]model.RelatedServer, error) {
+	cursor, err := m.related.Find(ctx, bson.M{"server_id": bson.M{"$in": serverIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query co-installs: %w", err)
+	}
+
+	var sets []model.CoInstalls
+	if err := cursor.All(ctx, &sets); err != nil {
+		return nil, fmt.Errorf("failed to decode co-installs: %w", err)
+	}
+
+	given := make(map[string]bool, len(serverIDs))
+	for _, serverID := range serverIDs {
+		given[serverID] = true
+	}
+
+	similar := make(map[string][]model.RelatedServer)
+	for _, set := range sets {
+		for _, related := range set.Related {
+			if given[related.ServerID] {
+				continue
+			}
+			candidate := related.ServerID
+			related.ServerID = set.ServerID
+			similar[candidate] = append(similar[candidate], related)
+		}
+	}
+
+	return similar, nil
+}
+
+// serverInstalls counts the users with an active install of each server,
+// and the users overall, among users the model learns from
+func (m *CoInstallModel) serverInstalls(ctx context.Context) (map[string]int64, int64, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		WITH eligible AS (
+			SELECT user_id, server_id
+			FROM user_installs
+			WHERE uninstalled_at IS NULL AND NOT flagged
+				AND user_id IN (
+					SELECT user_id FROM user_installs
+					WHERE uninstalled_at IS NULL AND NOT flagged
+					GROUP BY user_id HAVING COUNT(*) <= $1
+				)
+		)
+		SELECT server_id, COUNT(*), (SELECT COUNT(DISTINCT user_id) FROM eligible)
+		FROM eligible
+		GROUP BY server_id`, maxUserInstalls)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count installs: %w", err)
+	}
+	defer rows.Close()
+
+	installs := make(map[string]int64)
+	var users int64
+	for rows.Next() {
+		var serverID string
+		var count int64
+		if err := rows.Scan(&serverID, &count, &users); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan install count: %w", err)
+		}
+		installs[serverID] = count
+	}
+
+	return installs, users, rows.Err()
+}
+
+// save stores the strongest related servers of each server and removes
+// servers that no longer have any
+func (m *CoInstallModel) save(ctx context.Context, installs map[string]int64, related map[string][]model.RelatedServer, now time.Time) error {
+	writes := make([]mongo.WriteModel, 0, scoreUpdateBatch)
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		if _, err := m.related.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to save co-installs: %w", err)
+		}
+		writes = writes[:0]
+		return nil
+	}
+
+	for serverID, servers := range related {
+		sort.Slice(servers, func(i, j int) bool {
+			a, b := servers[i], servers[j]
+			if a.Jaccard != b.Jaccard {
+				return a.Jaccard > b.Jaccard
+			}
+			if a.CoInstalls != b.CoInstalls {
+				return a.CoInstalls > b.CoInstalls
+			}
+			return a.ServerID < b.ServerID
+		})
+		if len(servers) > maxRelated {
+			servers = servers[:maxRelated]
+		}
+
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"server_id": serverID}).
+			SetReplacement(&model.CoInstalls{
+				ServerID:  serverID,
+				Installs:  installs[serverID],
+				Related:   servers,
+				UpdatedAt: now,
+			}).
+			SetUpsert(true))
+		if len(writes) >= scoreUpdateBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if _, err := m.related.DeleteMany(ctx, bson.M{"updated_at": bson.M{"$lt": now}}); err != nil {
+		return fmt.Errorf("failed to prune co-installs: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,589 @@
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Experiment errors
+var (
+	ErrExperimentExists   = errors.New("experiment key already exists")
+	ErrExperimentConflict = errors.New("another experiment is running on the surface")
+	ErrExperimentState    = errors.New("experiment cannot make this transition")
+)
+
+// Experiment tuning
+const (
+	// exposureQueueSize bounds the exposures waiting to be written
+	exposureQueueSize = 10000
+	// experimentZ is the normal quantile of the reported 95% intervals
+	experimentZ          = 1.96
+	experimentConfidence = 0.95
+)
+
+const experimentColumns = `id, key, description, surface, status, variants, created_by, started_at, stopped_at, created_at, updated_at`
+
+// exposureKey identifies a unit in an experiment
+type exposureKey struct {
+	experimentID int64
+	unitType     string
+	unitID       string
+}
+
+// exposure is a unit seeing a variant
+type exposure struct {
+	exposureKey
+	variant string
+	at      time.Time
+}
+
+// exposureBatch aggregates a unit's exposures between writes
+type exposureBatch struct {
+	variant string
+	count   int64
+	first   time.Time
+	last    time.Time
+}
+
+// ExperimentStore persists ranking experiments, assigns units to their
+// variants and reports how the variants perform.
+//
+// Assignment hashes the experiment key with the unit, so a unit sees the
+// same variant on every request and instance without storing assignments.
+// Running experiments are cached in memory and reloaded by Refresh.
+// Exposures are queued and batched like search logs.
+type ExperimentStore struct {
+	db       *sql.DB
+	searches *SearchAnalytics
+
+	mu      sync.RWMutex
+	running map[string]*model.Experiment // by surface
+
+	queue         chan exposure
+	batchSize     int
+	flushInterval time.Duration
+	done          chan struct{}
+	stopped       chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewExperimentStore creates an experiment store, loads the running
+// experiments and starts the exposure writer. Search experiments are
+// measured with the clicks recorded by searches.
+func NewExperimentStore(db *sql.DB, searches *SearchAnalytics, batchSize int, flushInterval time.Duration) (*ExperimentStore, error) {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	s := &ExperimentStore{
+		db:            db,
+		searches:      searches,
+		running:       make(map[string]*model.Experiment),
+		queue:         make(chan exposure, exposureQueueSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	go s.run()
+
+	return s, nil
+}
+
+// Create stores a new experiment as a draft
+func (s *ExperimentStore) Create(ctx context.Context, experiment *model.Experiment) error {
+	variants, err := json.Marshal(experiment.Variants)
+	if err != nil {
+		return fmt.Errorf("failed to marshal variants: %w", err)
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO experiments (key, description, surface, variants, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key) DO NOTHING
+		RETURNING `+experimentColumns,
+		experiment.Key, experiment.Description, experiment.Surface, variants, experiment.CreatedBy,
+	)
+	err = scanExperiment(row, experiment)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrExperimentExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create experiment: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns an experiment
+func (s *ExperimentStore) Get(ctx context.Context, id int64) (*model.Experiment, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+experimentColumns+` FROM experiments WHERE id = $1`, id)
+
+	var experiment model.Experiment
+	if err := scanExperiment(row, &experiment); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get experiment: %w", err)
+	}
+
+	return &experiment, nil
+}
+
+// List returns experiments newest first, in a state or any state when
+// status is empty
+func (s *ExperimentStore) List(ctx context.Context, status string) ([]model.Experiment, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+experimentColumns+` FROM experiments
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC`, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiments: %w", err)
+	}
+	defer rows.Close()
+
+	experiments := []model.Experiment{}
+	for rows.Next() {
+		var experiment model.Experiment
+		if err := scanExperiment(rows, &experiment); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment: %w", err)
+		}
+		experiments = append(experiments, experiment)
+	}
+
+	return experiments, rows.Err()
+}
+
+// Start runs a draft experiment. Only one experiment may run per surface.
+func (s *ExperimentStore) Start(ctx context.Context, id int64) (*model.Experiment, error) {
+	experiment, err := s.transition(ctx, id, `
+		UPDATE experiments
+		SET status = 'running', started_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'draft'
+		RETURNING `+experimentColumns)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrExperimentConflict
+	}
+
+	return experiment, err
+}
+
+// Stop ends a running experiment. Its units return to the default ranking.
+func (s *ExperimentStore) Stop(ctx context.Context, id int64) (*model.Experiment, error) {
+	return s.transition(ctx, id, `
+		UPDATE experiments
+		SET status = 'stopped', stopped_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+		RETURNING `+experimentColumns)
+}
+
+// transition applies a status update and reloads the running experiments
+func (s *ExperimentStore) transition(ctx context.Context, id int64, query string) (*model.Experiment, error) {
+	var experiment model.Experiment
+	err := scanExperiment(s.db.QueryRowContext(ctx, query, id), &experiment)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := s.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrExperimentState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update experiment: %w", err)
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return &experiment, nil
+}
+
+// Refresh reloads the running experiments. It runs as a scheduled job so
+// every instance picks up experiments started or stopped elsewhere.
+func (s *ExperimentStore) Refresh(ctx context.Context) error {
+	experiments, err := s.List(ctx, model.ExperimentRunning)
+	if err != nil {
+		return err
+	}
+
+	running := make(map[string]*model.Experiment, len(experiments))
+	for i := range experiments {
+		running[experiments[i].Surface] = &experiments[i]
+	}
+
+	s.mu.Lock()
+	s.running = running
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Assign returns the unit's variant in the experiment running on a surface
+// and logs the exposure. It returns nil when no experiment is running or
+// the unit is unknown.
+func (s *ExperimentStore) Assign(surface, unitType, unitID string) *model.ExperimentAssignment {
+	if unitID == "" {
+		return nil
+	}
+
+	s.mu.RLock()
+	experiment := s.running[surface]
+	s.mu.RUnlock()
+	if experiment == nil {
+		return nil
+	}
+
+	variant := assignVariant(experiment, unitType, unitID)
+	if variant == nil {
+		return nil
+	}
+
+	select {
+	case s.queue <- exposure{
+		exposureKey: exposureKey{experimentID: experiment.ID, unitType: unitType, unitID: unitID},
+		variant:     variant.Name,
+		at:          time.Now().UTC(),
+	}:
+	default:
+		log.Printf("Experiment exposure queue full, dropping exposure")
+	}
+
+	return &model.ExperimentAssignment{
+		ExperimentID: experiment.ID,
+		Key:          experiment.Key,
+		Variant:      variant.Name,
+		Params:       variant.Params,
+	}
+}
+
+// assignVariant picks a variant by hashing the experiment key and unit
+// into the variants' cumulative weights
+func assignVariant(experiment *model.Experiment, unitType, unitID string) *model.ExperimentVariant {
+	var total uint64
+	for _, variant := range experiment.Variants {
+		total += uint64(variant.Weight)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(experiment.Key + ":" + unitType + ":" + unitID))
+	bucket := binary.BigEndian.Uint64(sum[:8]) % total
+
+	for i := range experiment.Variants {
+		weight := uint64(experiment.Variants[i].Weight)
+		if bucket < weight {
+			return &experiment.Variants[i]
+		}
+		bucket -= weight
+	}
+
+	return nil
+}
+
+// Close stops the exposure writer after flushing queued exposures, waiting
+// at most until the context is done
+func (s *ExperimentStore) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() { close(s.done) })
+
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush experiment exposures: %w", ctx.Err())
+	}
+}
+
+// run batches queued exposures and writes them on size or interval
+func (s *ExperimentStore) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make(map[exposureKey]*exposureBatch)
+	flush := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := s.writeExposures(ctx, batch); err != nil {
+			log.Printf("Failed to write %d experiment exposures: %v", len(batch), err)
+		}
+		batch = make(map[exposureKey]*exposureBatch)
+	}
+	add := func(e exposure) {
+		b, ok := batch[e.exposureKey]
+		if !ok {
+			batch[e.exposureKey] = &exposureBatch{variant: e.variant, count: 1, first: e.at, last: e.at}
+			return
+		}
+		b.count++
+		b.last = e.at
+	}
+
+	for {
+		select {
+		case e := <-s.queue:
+			add(e)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-s.done:
+			// Drain what is already queued
+			for {
+				select {
+				case e := <-s.queue:
+					add(e)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// writeExposures upserts a batch, keeping each unit's first exposure
+func (s *ExperimentStore) writeExposures(ctx context.Context, batch map[exposureKey]*exposureBatch) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var (
+		experimentIDs           pq.Int64Array
+		unitTypes, unitIDs      pq.StringArray
+		variants, firsts, lasts pq.StringArray
+		counts                  pq.Int64Array
+	)
+	for key, b := range batch {
+		experimentIDs = append(experimentIDs, key.experimentID)
+		unitTypes = append(unitTypes, key.unitType)
+		unitIDs = append(unitIDs, key.unitID)
+		variants = append(variants, b.variant)
+		counts = append(counts, b.count)
+		firsts = append(firsts, b.first.Format(time.RFC3339Nano))
+		lasts = append(lasts, b.last.Format(time.RFC3339Nano))
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO experiment_exposures
+			(experiment_id, unit_type, unit_id, variant, exposures, first_exposed_at, last_exposed_at)
+		SELECT * FROM unnest($1::BIGINT[], $2::TEXT[], $3::TEXT[], $4::TEXT[], $5::BIGINT[],
+			$6::TIMESTAMPTZ[], $7::TIMESTAMPTZ[])
+		ON CONFLICT (experiment_id, unit_type, unit_id) DO UPDATE
+		SET exposures = experiment_exposures.exposures + EXCLUDED.exposures,
+			last_exposed_at = GREATEST(experiment_exposures.last_exposed_at, EXCLUDED.last_exposed_at)`,
+		experimentIDs, unitTypes, unitIDs, variants, counts, firsts, lasts,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write exposures: %w", err)
+	}
+
+	return nil
+}
+
+// Report compares an experiment's variants. Search experiments measure
+// click-through over the variant's logged searches, counting those with a
+// result clicked. On discovery surfaces, which record no clicks, a unit
+// counts as clicked when it viewed a server. A unit counts as converted
+// when it installed a server. Views and installs count after the unit's
+// first exposure and before the experiment stopped. Installs need a user,
+// so session units only contribute to click-through.
+func (s *ExperimentStore) Report(ctx context.Context, id int64) (*model.ExperimentReport, error) {
+	experiment, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	to := time.Now().UTC()
+	if experiment.StoppedAt != nil {
+		to = *experiment.StoppedAt
+	}
+
+	metric := model.ClickThroughServerViews
+	if experiment.Surface == model.ExperimentSearch {
+		metric = model.ClickThroughSearchClicks
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			e.variant,
+			COUNT(*),
+			COALESCE(SUM(e.exposures), 0),
+			COUNT(*) FILTER (WHERE $3 AND (
+				(e.unit_type = 'user' AND EXISTS (
+					SELECT 1 FROM server_views v
+					WHERE v.user_id = e.unit_id AND v.created_at >= e.first_exposed_at AND v.created_at < $2))
+				OR (e.unit_type = 'session' AND EXISTS (
+					SELECT 1 FROM server_views v
+					WHERE v.session_id = e.unit_id AND v.created_at >= e.first_exposed_at AND v.created_at < $2)))),
+			COUNT(*) FILTER (WHERE e.unit_type = 'user' AND EXISTS (
+				SELECT 1 FROM user_installs i
+				WHERE i.user_id = e.unit_id AND NOT i.flagged
+					AND i.installed_at >= e.first_exposed_at AND i.installed_at < $2))
+		FROM experiment_exposures e
+		WHERE e.experiment_id = $1
+		GROUP BY e.variant`, id, to, metric == model.ClickThroughServerViews)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query experiment results: %w", err)
+	}
+	defer rows.Close()
+
+	results := make(map[string]*model.VariantReport)
+	for rows.Next() {
+		var r model.VariantReport
+		if err := rows.Scan(&r.Variant, &r.Units, &r.Exposures, &r.ClickedUnits, &r.InstalledUnits); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment result: %w", err)
+		}
+		results[r.Variant] = &r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query experiment results: %w", err)
+	}
+
+	var searches map[string]VariantSearches
+	if metric == model.ClickThroughSearchClicks {
+		if searches, err = s.searches.ExperimentSearches(ctx, id, to); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &model.ExperimentReport{
+		Experiment:         *experiment,
+		To:                 to,
+		Confidence:         experimentConfidence,
+		ClickThroughMetric: metric,
+		Variants:           make([]model.VariantReport, 0, len(experiment.Variants)),
+	}
+
+	// Variants in their defined order, the control first
+	var control *model.VariantReport
+	for i, variant := range experiment.Variants {
+		r, ok := results[variant.Name]
+		if !ok {
+			r = &model.VariantReport{Variant: variant.Name}
+		}
+		// Click-through counts clicked searches or clicked units
+		clicked, trials := r.ClickedUnits, r.Units
+		if metric == model.ClickThroughSearchClicks {
+			r.Searches, r.ClickedSearches = searches[variant.Name].Searches, searches[variant.Name].Clicked
+			clicked, trials = r.ClickedSearches, r.Searches
+		}
+		r.ClickThrough = wilsonInterval(clicked, trials)
+		r.InstallConversion = wilsonInterval(r.InstalledUnits, r.Units)
+
+		if i == 0 {
+			r.Control = true
+			control = r
+		} else {
+			controlClicked, controlTrials := control.ClickedUnits, control.Units
+			if metric == model.ClickThroughSearchClicks {
+				controlClicked, controlTrials = control.ClickedSearches, control.Searches
+			}
+			r.ClickThroughDiff = rateDifference(clicked, trials, controlClicked, controlTrials)
+			r.InstallDiff = rateDifference(r.InstalledUnits, r.Units, control.InstalledUnits, control.Units)
+		}
+
+		report.Variants = append(report.Variants, *r)
+	}
+
+	return report, nil
+}
+
+// wilsonInterval estimates a proportion with the Wilson score interval,
+// which stays within [0, 1] and behaves for small counts
+func wilsonInterval(successes, trials int64) model.RateEstimate {
+	if trials == 0 {
+		return model.RateEstimate{}
+	}
+
+	n := float64(trials)
+	p := float64(successes) / n
+	z2 := experimentZ * experimentZ
+
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	margin := experimentZ * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / (1 + z2/n)
+
+	return model.RateEstimate{
+		Rate:  roundScore(p),
+		Lower: roundScore(math.Max(0, center-margin)),
+		Upper: roundScore(math.Min(1, center+margin)),
+	}
+}
+
+// rateDifference estimates a variant's proportion minus the control's with
+// a normal approximation interval. It returns nil when either arm is empty.
+func rateDifference(successes, trials, controlSuccesses, controlTrials int64) *model.RateDifference {
+	if trials == 0 || controlTrials == 0 {
+		return nil
+	}
+
+	p1 := float64(successes) / float64(trials)
+	p0 := float64(controlSuccesses) / float64(controlTrials)
+	diff := p1 - p0
+	stderr := math.Sqrt(p1*(1-p1)/float64(trials) + p0*(1-p0)/float64(controlTrials))
+	lower, upper := diff-experimentZ*stderr, diff+experimentZ*stderr
+
+	return &model.RateDifference{
+		Difference:  roundScore(diff),
+		Lower:       roundScore(lower),
+		Upper:       roundScore(upper),
+		Significant: lower > 0 || upper < 0,
+	}
+}
+
+// scanExperiment scans experimentColumns into experiment
+func scanExperiment(row rowScanner, experiment *model.Experiment) error {
+	var variants []byte
+	var startedAt, stoppedAt sql.NullTime
+
+	if err := row.Scan(
+		&experiment.ID, &experiment.Key, &experiment.Description, &experiment.Surface, &experiment.Status,
+		&variants, &experiment.CreatedBy, &startedAt, &stoppedAt, &experiment.CreatedAt, &experiment.UpdatedAt,
+	); err != nil {
+		return err
+	}
+
+	experiment.Variants = nil
+	if err := json.Unmarshal(variants, &experiment.Variants); err != nil {
+		return fmt.Errorf("failed to decode experiment variants: %w", err)
+	}
+
+	experiment.StartedAt = nil
+	if startedAt.Valid {
+		experiment.StartedAt = &startedAt.Time
+	}
+	experiment.StoppedAt = nil
+	if stoppedAt.Valid {
+		experiment.StoppedAt = &stoppedAt.Time
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// ErrNotFound is returned when a requested record doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// Featured placement statuses used for admin listing
+const (
+	FeaturedStatusActive    = "active"
+	FeaturedStatusScheduled = "scheduled"
+	FeaturedStatusArchived  = "archived"
+)
+
+const featuredColumns = `id, server_id, category, blurb, display_order, starts_at, ends_at,
+	archived_at, created_by, created_at, updated_at`
+
+// FeaturedStore persists admin-curated featured placements in PostgreSQL
+type FeaturedStore struct {
+	db *sql.DB
+}
+
+// NewFeaturedStore creates a new featured store
+func NewFeaturedStore(db *sql.DB) *FeaturedStore {
+	return &FeaturedStore{db: db}
+}
+
+// Create inserts a new featured placement and fills in its generated fields
+func (s *FeaturedStore) Create(ctx context.Context, f *model.FeaturedServer) error {
+	if f.StartsAt.IsZero() {
+		f.StartsAt = time.Now()
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO featured_servers (server_id, category, blurb, display_order, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+featuredColumns,
+		f.ServerID, f.Category, f.Blurb, f.DisplayOrder, f.StartsAt, f.EndsAt, f.CreatedBy,
+	)
+
+	if err := scanFeatured(row, f); err != nil {
+		return fmt.Errorf("failed to create featured server: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces the editable fields of a featured placement
+func (s *FeaturedStore) Update(ctx context.Context, f *model.FeaturedServer) error {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE featured_servers
+		SET category = $2, blurb = $3, display_order = $4, starts_at = $5, ends_at = $6, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+featuredColumns,
+		f.ID, f.Category, f.Blurb, f.DisplayOrder, f.StartsAt, f.EndsAt,
+	)
+
+	if err := scanFeatured(row, f); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update featured server: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a featured placement
+func (s *FeaturedStore) Delete(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM featured_servers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete featured server: %w", err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Get retrieves a featured placement by ID
+func (s *FeaturedStore) Get(ctx context.Context, id int64) (*model.FeaturedServer, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+featuredColumns+` FROM featured_servers WHERE id = $1`, id)
+
+	var f model.FeaturedServer
+	if err := scanFeatured(row, &f); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get featured server: %w", err)
+	}
+
+	return &f, nil
+}
+
+// List returns placements for the admin interface, optionally filtered by status
+func (s *FeaturedStore) List(ctx context.Context, status string) ([]model.FeaturedServer, error) {
+	where := "TRUE"
+	switch status {
+	case FeaturedStatusActive:
+		where = "archived_at IS NULL AND starts_at <= NOW() AND (ends_at IS NULL OR ends_at > NOW())"
+	case FeaturedStatusScheduled:
+		where = "archived_at IS NULL AND starts_at > NOW()"
+	case FeaturedStatusArchived:
+		where = "archived_at IS NOT NULL"
+	}
+
+	return s.query(ctx, `SELECT `+featuredColumns+` FROM featured_servers WHERE `+where+`
+		ORDER BY category, display_order, starts_at DESC`)
+}
+
+// Active returns the placements currently live for a category ("" for global)
+func (s *FeaturedStore) Active(ctx context.Context, category string, limit int) ([]model.FeaturedServer, error) {
+	return s.query(ctx, `SELECT `+featuredColumns+` FROM featured_servers
+		WHERE category = $1
+		  AND archived_at IS NULL
+		  AND starts_at <= NOW()
+		  AND (ends_at IS NULL OR ends_at > NOW())
+		ORDER BY display_order, starts_at DESC
+		LIMIT $2`, category, limit)
+}
+
+// ArchiveExpired archives placements whose end time has passed and returns how many were archived
+func (s *FeaturedStore) ArchiveExpired(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE featured_servers
+		SET archived_at = NOW(), updated_at = NOW()
+		WHERE archived_at IS NULL AND ends_at IS NOT NULL AND ends_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive expired featured servers: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// Started returns how many placements went live within (from, to]
+func (s *FeaturedStore) Started(ctx context.Context, from, to time.Time) (int64, error) {
+	var started int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM featured_servers
+		WHERE archived_at IS NULL AND starts_at > $1 AND starts_at <= $2`, from, to,
+	).Scan(&started)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count started featured servers: %w", err)
+	}
+
+	return started, nil
+}
+
+// query runs a select over featured_servers and scans every row
+func (s *FeaturedStore) query(ctx context.Context, query string, args ...interface{}) ([]model.FeaturedServer, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query featured servers: %w", err)
+	}
+	defer rows.Close()
+
+	featured := []model.FeaturedServer{}
+	for rows.Next() {
+		var f model.FeaturedServer
+		if err := scanFeatured(rows, &f); err != nil {
+			return nil, fmt.Errorf("failed to scan featured server: %w", err)
+		}
+		featured = append(featured, f)
+	}
+
+	return featured, rows.Err()
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFeatured scans featuredColumns into f
+func scanFeatured(row rowScanner, f *model.FeaturedServer) error {
+	var endsAt, archivedAt sql.NullTime
+
+	if err := row.Scan(
+		&f.ID, &f.ServerID, &f.Category, &f.Blurb, &f.DisplayOrder, &f.StartsAt, &endsAt,
+		&archivedAt, &f.CreatedBy, &f.CreatedAt, &f.UpdatedAt,
+	); err != nil {
+		return err
+	}
+
+	f.EndsAt = nil
+	if endsAt.Valid {
+		f.EndsAt = &endsAt.Time
+	}
+	f.ArchivedAt = nil
+	if archivedAt.Valid {
+		f.ArchivedAt = &archivedAt.Time
+	}
+
+	return nil
+}
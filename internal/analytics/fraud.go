@@ -0,0 +1,597 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Fraud flag subjects and states
+const (
+	FraudSubjectServer = "server"
+	FraudSubjectUser   = "user"
+
+	FraudOpen      = "open"
+	FraudDismissed = "dismissed"
+)
+
+// Fraud detection rules
+const (
+	RuleIPBurst         = "ip_burst"
+	RuleZeroUsage       = "zero_usage"
+	RuleQuickUninstall  = "quick_uninstall"
+	RuleSequentialUsers = "sequential_users"
+)
+
+// Fraud detection tuning
+const (
+	// fraudWindow is how far back each run looks for suspicious installs
+	fraudWindow = 7 * 24 * time.Hour
+	// ipBurstThreshold installs of one server from one IP and user agent
+	// within an hour count as a burst
+	ipBurstThreshold = 10
+	// zeroUsageMinInstalls, zeroUsageGrace and zeroUsageMaxRate flag servers
+	// whose installers almost never use them once they had time to
+	zeroUsageMinInstalls = 20
+	zeroUsageGrace       = 24 * time.Hour
+	zeroUsageMaxRate     = 0.05
+	// quickUninstallAge is how soon an uninstall follows a suspicious install
+	quickUninstallAge = 5 * time.Minute
+	// quickUninstallServerMin and quickUninstallServerRate flag servers whose
+	// installs are mostly removed right away
+	quickUninstallServerMin  = 10
+	quickUninstallServerRate = 0.5
+	// quickUninstallUserMin flags accounts that keep installing and removing
+	quickUninstallUserMin = 3
+	// sequentialRunLength accounts with consecutive numeric suffixes that
+	// install the same server within an hour look synthetic
+	sequentialRunLength = 5
+	sequentialMaxGap    = 2
+)
+
+// fraudDetection is a rule match awaiting storage
+type fraudDetection struct {
+	subjectType string
+	subjectID   string
+	rule        string
+	evidence    []map[string]interface{}
+	installIDs  []int64
+}
+
+// FraudDetector flags install patterns that inflate popularity and trending.
+// Detections are stored per subject and rule; installs covered by open flags
+// are marked flagged so counts and scores ignore them until an admin
+// dismisses the flag. A dismissal clears the installs it covered; detections
+// of other installs reopen the flag.
+type FraudDetector struct {
+	db          *sql.DB
+	usage       *mongo.Collection
+	indexSyncer *IndexSyncer
+}
+
+// NewFraudDetector creates a new fraud detector
+func NewFraudDetector(db *sql.DB, mongoDB *mongo.Database, indexSyncer *IndexSyncer) *FraudDetector {
+	return &FraudDetector{
+		db:          db,
+		usage:       mongoDB.Collection(UsageEventsCollection),
+		indexSyncer: indexSyncer,
+	}
+}
+
+// Run evaluates every rule over recent installs, stores the detections and
+// updates which installs are flagged. It runs as a scheduled job.
+func (d *FraudDetector) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+	since := now.Add(-fraudWindow)
+
+	rules := []func(context.Context, time.Time, time.Time) ([]fraudDetection, error){
+		d.ipBursts,
+		d.zeroUsage,
+		d.quickUninstalls,
+		d.sequentialUsers,
+	}
+
+	var detections []fraudDetection
+	for _, rule := range rules {
+		found, err := rule(ctx, since, now)
+		if err != nil {
+			return err
+		}
+		detections = append(detections, found...)
+	}
+
+	for _, detection := range mergeDetections(detections) {
+		if err := d.save(ctx, detection); err != nil {
+			return err
+		}
+	}
+
+	flagged, cleared, err := d.syncFlagged(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(detections) > 0 || flagged+cleared > 0 {
+		log.Printf("Fraud detection: %d detections, %d installs flagged, %d cleared", len(detections), flagged, cleared)
+	}
+
+	return nil
+}
+
+// Flags returns a page of fraud flags in a state, optionally for one
+// subject type, most recently detected first
+func (d *FraudDetector) Flags(ctx context.Context, status, subjectType string, offset, limit int) ([]model.FraudFlag, int, error) {
+	var total int
+	if err := d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM fraud_flags
+		WHERE status = $1 AND ($2 = '' OR subject_type = $2)`, status, subjectType,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count fraud flags: %w", err)
+	}
+
+	rows, err := d.db.QueryContext(ctx, `SELECT `+fraudFlagColumns+` FROM fraud_flags
+		WHERE status = $1 AND ($2 = '' OR subject_type = $2)
+		ORDER BY last_detected_at DESC, id
+		LIMIT $3 OFFSET $4`, status, subjectType, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query fraud flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []model.FraudFlag{}
+	for rows.Next() {
+		var flag model.FraudFlag
+		if err := scanFraudFlag(rows, &flag); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan fraud flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags, total, rows.Err()
+}
+
+// Dismiss marks a flag as a false positive. Its installs count again unless
+// another open flag covers them, and the rule no longer fires for the subject
+// on those installs.
+func (d *FraudDetector) Dismiss(ctx context.Context, id int64, reviewer string) (*model.FraudFlag, error) {
+	var flag model.FraudFlag
+	err := scanFraudFlag(d.db.QueryRowContext(ctx, `
+		UPDATE fraud_flags
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW(),
+			reviewed_install_ids = ARRAY(SELECT DISTINCT unnest(reviewed_install_ids || install_ids))
+		WHERE id = $1
+		RETURNING `+fraudFlagColumns, id, FraudDismissed, reviewer,
+	), &flag)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dismiss fraud flag: %w", err)
+	}
+
+	if _, _, err := d.syncFlagged(ctx); err != nil {
+		return nil, err
+	}
+
+	return &flag, nil
+}
+
+// ipBursts finds many installs of a server from one IP and user agent
+// within the same hour. Installs without a client IP are skipped.
+func (d *FraudDetector) ipBursts(ctx context.Context, since, _ time.Time) ([]fraudDetection, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT server_id, ip_address, user_agent, date_trunc('hour', installed_at), COUNT(*), array_agg(id)
+		FROM user_installs
+		WHERE installed_at >= $1 AND ip_address <> ''
+		GROUP BY 1, 2, 3, 4
+		HAVING COUNT(*) >= $2`, since, ipBurstThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query install bursts: %w", err)
+	}
+	defer rows.Close()
+
+	var detections []fraudDetection
+	for rows.Next() {
+		var serverID, ip, userAgent string
+		var hour time.Time
+		var count int
+		var ids pq.Int64Array
+		if err := rows.Scan(&serverID, &ip, &userAgent, &hour, &count, &ids); err != nil {
+			return nil, fmt.Errorf("failed to scan install burst: %w", err)
+		}
+
+		detections = append(detections, fraudDetection{
+			subjectType: FraudSubjectServer,
+			subjectID:   serverID,
+			rule:        RuleIPBurst,
+			evidence: []map[string]interface{}{{
+				"ip_address": ip,
+				"user_agent": userAgent,
+				"hour":       hour.UTC(),
+				"installs":   count,
+			}},
+			installIDs: ids,
+		})
+	}
+
+	return detections, rows.Err()
+}
+
+// zeroUsage finds servers whose installers almost never send usage events
+func (d *FraudDetector) zeroUsage(ctx context.Context, since, now time.Time) ([]fraudDetection, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT server_id, array_agg(id), array_agg(user_id)
+		FROM user_installs
+		WHERE installed_at >= $1 AND installed_at < $2
+		GROUP BY server_id
+		HAVING COUNT(*) >= $3`, since, now.Add(-zeroUsageGrace), zeroUsageMinInstalls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query installs for usage check: %w", err)
+	}
+
+	type candidate struct {
+		serverID string
+		ids      pq.Int64Array
+		users    pq.StringArray
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.serverID, &c.ids, &c.users); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan installs for usage check: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query installs for usage check: %w", err)
+	}
+
+	var detections []fraudDetection
+	for _, c := range candidates {
+		active, err := d.usage.Distinct(ctx, "user_id", bson.M{
+			"server_id": c.serverID,
+			"user_id":   bson.M{"$in": []string(c.users)},
+			"timestamp": bson.M{"$gte": since},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query server usage: %w", err)
+		}
+
+		used := make(map[string]bool, len(active))
+		for _, user := range active {
+			if id, ok := user.(string); ok {
+				used[id] = true
+			}
+		}
+
+		var unused []int64
+		for i, user := range c.users {
+			if !used[user] {
+				unused = append(unused, c.ids[i])
+			}
+		}
+
+		rate := 1 - float64(len(unused))/float64(len(c.ids))
+		if rate >= zeroUsageMaxRate {
+			continue
+		}
+
+		detections = append(detections, fraudDetection{
+			subjectType: FraudSubjectServer,
+			subjectID:   c.serverID,
+			rule:        RuleZeroUsage,
+			evidence: []map[string]interface{}{{
+				"installs":        len(c.ids),
+				"installs_unused": len(unused),
+				"usage_rate":      roundScore(rate),
+			}},
+			installIDs: unused,
+		})
+	}
+
+	return detections, nil
+}
+
+// quickUninstalls finds servers whose installs are mostly removed right away
+// and accounts that repeatedly install and remove servers
+func (d *FraudDetector) quickUninstalls(ctx context.Context, since, _ time.Time) ([]fraudDetection, error) {
+	var detections []fraudDetection
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT server_id, COUNT(*),
+			array_agg(id) FILTER (WHERE uninstalled_at - installed_at < $2::interval)
+		FROM user_installs
+		WHERE installed_at >= $1
+		GROUP BY server_id
+		HAVING COUNT(*) FILTER (WHERE uninstalled_at - installed_at < $2::interval) >= $3`,
+		since, pgInterval(quickUninstallAge), quickUninstallServerMin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quick uninstalls: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var serverID string
+		var installs int
+		var ids pq.Int64Array
+		if err := rows.Scan(&serverID, &installs, &ids); err != nil {
+			return nil, fmt.Errorf("failed to scan quick uninstalls: %w", err)
+		}
+
+		rate := float64(len(ids)) / float64(installs)
+		if rate < quickUninstallServerRate {
+			continue
+		}
+
+		detections = append(detections, fraudDetection{
+			subjectType: FraudSubjectServer,
+			subjectID:   serverID,
+			rule:        RuleQuickUninstall,
+			evidence: []map[string]interface{}{{
+				"installs":         installs,
+				"quick_uninstalls": len(ids),
+				"rate":             roundScore(rate),
+			}},
+			installIDs: ids,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query quick uninstalls: %w", err)
+	}
+
+	userRows, err := d.db.QueryContext(ctx, `
+		SELECT user_id, COUNT(DISTINCT server_id), array_agg(id)
+		FROM user_installs
+		WHERE installed_at >= $1 AND uninstalled_at - installed_at < $2::interval
+		GROUP BY user_id
+		HAVING COUNT(*) >= $3`,
+		since, pgInterval(quickUninstallAge), quickUninstallUserMin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quick uninstall accounts: %w", err)
+	}
+	defer userRows.Close()
+
+	for userRows.Next() {
+		var userID string
+		var servers int
+		var ids pq.Int64Array
+		if err := userRows.Scan(&userID, &servers, &ids); err != nil {
+			return nil, fmt.Errorf("failed to scan quick uninstall account: %w", err)
+		}
+
+		detections = append(detections, fraudDetection{
+			subjectType: FraudSubjectUser,
+			subjectID:   userID,
+			rule:        RuleQuickUninstall,
+			evidence: []map[string]interface{}{{
+				"quick_uninstalls": len(ids),
+				"servers":          servers,
+			}},
+			installIDs: ids,
+		})
+	}
+
+	return detections, userRows.Err()
+}
+
+// sequentialUsers finds accounts whose IDs differ only by consecutive
+// numeric suffixes installing the same server within the same hour
+func (d *FraudDetector) sequentialUsers(ctx context.Context, since, _ time.Time) ([]fraudDetection, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT server_id, substring(user_id from '^(.*?)[0-9]+$'), date_trunc('hour', installed_at),
+			array_agg(user_id), array_agg(id)
+		FROM user_installs
+		WHERE installed_at >= $1 AND user_id ~ '[0-9]+$'
+		GROUP BY 1, 2, 3
+		HAVING COUNT(*) >= $2`, since, sequentialRunLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sequential accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var detections []fraudDetection
+	for rows.Next() {
+		var serverID, prefix string
+		var hour time.Time
+		var users pq.StringArray
+		var ids pq.Int64Array
+		if err := rows.Scan(&serverID, &prefix, &hour, &users, &ids); err != nil {
+			return nil, fmt.Errorf("failed to scan sequential accounts: %w", err)
+		}
+
+		run := sequentialRun(prefix, users)
+		if len(run) < sequentialRunLength {
+			continue
+		}
+
+		for i, user := range users {
+			if !run[user] {
+				continue
+			}
+			detections = append(detections, fraudDetection{
+				subjectType: FraudSubjectUser,
+				subjectID:   user,
+				rule:        RuleSequentialUsers,
+				evidence: []map[string]interface{}{{
+					"server_id":  serverID,
+					"prefix":     prefix,
+					"hour":       hour.UTC(),
+					"run_length": len(run),
+				}},
+				installIDs: []int64{ids[i]},
+			})
+		}
+	}
+
+	return detections, rows.Err()
+}
+
+// sequentialRun returns the users in the longest run of numeric suffixes
+// that are at most sequentialMaxGap apart
+func sequentialRun(prefix string, users []string) map[string]bool {
+	bySuffix := make(map[int64]string, len(users))
+	suffixes := make([]int64, 0, len(users))
+	for _, user := range users {
+		n, err := strconv.ParseInt(user[len(prefix):], 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, ok := bySuffix[n]; !ok {
+			suffixes = append(suffixes, n)
+		}
+		bySuffix[n] = user
+	}
+	sort.Slice(suffixes, func(i, j int) bool { return suffixes[i] < suffixes[j] })
+
+	bestStart, bestLen := 0, 0
+	for start := 0; start < len(suffixes); {
+		end := start + 1
+		for end < len(suffixes) && suffixes[end]-suffixes[end-1] <= sequentialMaxGap {
+			end++
+		}
+		if end-start > bestLen {
+			bestStart, bestLen = start, end-start
+		}
+		start = end
+	}
+
+	run := make(map[string]bool, bestLen)
+	for _, n := range suffixes[bestStart : bestStart+bestLen] {
+		run[bySuffix[n]] = true
+	}
+	return run
+}
+
+// mergeDetections combines detections of the same subject and rule
+func mergeDetections(detections []fraudDetection) []fraudDetection {
+	type key struct{ subjectType, subjectID, rule string }
+
+	index := make(map[key]int)
+	var merged []fraudDetection
+	for _, detection := range detections {
+		k := key{detection.subjectType, detection.subjectID, detection.rule}
+		if i, ok := index[k]; ok {
+			merged[i].evidence = append(merged[i].evidence, detection.evidence...)
+			merged[i].installIDs = append(merged[i].installIDs, detection.installIDs...)
+			continue
+		}
+		index[k] = len(merged)
+		merged = append(merged, detection)
+	}
+
+	return merged
+}
+
+// save upserts a detection. Installs flagged by earlier runs stay flagged.
+// Installs an admin already cleared are left out, and a dismissed flag is
+// reopened only when the detection covers other installs.
+func (d *FraudDetector) save(ctx context.Context, detection fraudDetection) error {
+	evidence, err := json.Marshal(detection.evidence)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fraud evidence: %w", err)
+	}
+
+	_, err = d.db.ExecContext(ctx, `
+		INSERT INTO fraud_flags (subject_type, subject_id, rule, evidence, install_ids)
+		VALUES ($1, $2, $3, $4, ARRAY(SELECT DISTINCT unnest($5::bigint[])))
+		ON CONFLICT (subject_type, subject_id, rule) DO UPDATE
+		SET status = $6,
+			evidence = EXCLUDED.evidence,
+			install_ids = ARRAY(
+				SELECT DISTINCT id FROM unnest(fraud_flags.install_ids || EXCLUDED.install_ids) AS id
+				WHERE id <> ALL(fraud_flags.reviewed_install_ids)
+			),
+			last_detected_at = NOW(),
+			reviewed_by = '',
+			reviewed_at = NULL
+		WHERE EXISTS (
+			SELECT 1 FROM unnest(EXCLUDED.install_ids) AS id
+			WHERE id <> ALL(fraud_flags.reviewed_install_ids)
+		)`,
+		detection.subjectType, detection.subjectID, detection.rule, evidence,
+		pq.Array(detection.installIDs), FraudOpen,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save fraud flag: %w", err)
+	}
+
+	return nil
+}
+
+// syncFlagged sets the flagged column to match the open fraud flags. Touched
+// installs get a new updated_at so the metrics rollup recounts their hours,
+// and their servers' index stats are refreshed.
+func (d *FraudDetector) syncFlagged(ctx context.Context) (flagged, cleared int, err error) {
+	rows, err := d.db.QueryContext(ctx, `
+		WITH open_installs AS (
+			SELECT DISTINCT unnest(install_ids) AS id FROM fraud_flags WHERE status = $1
+		)
+		UPDATE user_installs u
+		SET flagged = NOT u.flagged, updated_at = NOW()
+		WHERE (u.flagged AND u.id NOT IN (SELECT id FROM open_installs))
+		   OR (NOT u.flagged AND u.id IN (SELECT id FROM open_installs))
+		RETURNING u.server_id, u.flagged`, FraudOpen)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to update flagged installs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var serverID string
+		var isFlagged bool
+		if err := rows.Scan(&serverID, &isFlagged); err != nil {
+			return 0, 0, fmt.Errorf("failed to scan flagged install: %w", err)
+		}
+		if isFlagged {
+			flagged++
+		} else {
+			cleared++
+		}
+		d.indexSyncer.MarkDirty(serverID)
+	}
+
+	return flagged, cleared, rows.Err()
+}
+
+// fraudFlagColumns are the fraud_flags columns read by scanFraudFlag
+const fraudFlagColumns = `id, subject_type, subject_id, rule, status, evidence, cardinality(install_ids),
+	first_detected_at, last_detected_at, reviewed_by, reviewed_at`
+
+// scanFraudFlag scans fraudFlagColumns into flag
+func scanFraudFlag(row rowScanner, flag *model.FraudFlag) error {
+	var evidence []byte
+	var reviewedAt sql.NullTime
+
+	if err := row.Scan(
+		&flag.ID, &flag.SubjectType, &flag.SubjectID, &flag.Rule, &flag.Status, &evidence,
+		&flag.InstallCount, &flag.FirstDetectedAt, &flag.LastDetectedAt, &flag.ReviewedBy, &reviewedAt,
+	); err != nil {
+		return err
+	}
+
+	flag.Evidence = []map[string]interface{}{}
+	if err := json.Unmarshal(evidence, &flag.Evidence); err != nil {
+		return fmt.Errorf("failed to decode fraud evidence: %w", err)
+	}
+
+	flag.ReviewedAt = nil
+	if reviewedAt.Valid {
+		flag.ReviewedAt = &reviewedAt.Time
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// surfaceKeywords maps source and referrer fragments to discovery surfaces,
+// e.g. "search_results" and "trending_list"
+var surfaceKeywords = []struct {
+	keyword string
+	surface string
+}{
+	{"search", model.SurfaceSearch},
+	{"trending", model.SurfaceTrending},
+	{"featured", model.SurfaceFeatured},
+	{"similar", model.SurfaceSimilar},
+	{"recommend", model.SurfaceSimilar},
+	{"related", model.SurfaceSimilar},
+	{"also", model.SurfaceSimilar},
+	{"direct", model.SurfaceDirect},
+}
+
+// Surface returns the discovery surface for a client-reported source,
+// falling back to the referrer when the source is not recognized
+func Surface(source, referrer string) string {
+	for _, value := range []string{source, referrer} {
+		value = strings.ToLower(value)
+		for _, k := range surfaceKeywords {
+			if strings.Contains(value, k.keyword) {
+				return k.surface
+			}
+		}
+	}
+
+	if source == "" && referrer == "" {
+		return model.SurfaceDirect
+	}
+	return model.SurfaceOther
+}
+
+// FunnelStore records server views and reports discovery funnels from
+// search impressions through views to installs
+type FunnelStore struct {
+	db              *sql.DB
+	searchAnalytics *SearchAnalytics
+}
+
+// NewFunnelStore creates a new funnel store
+func NewFunnelStore(db *sql.DB, searchAnalytics *SearchAnalytics) *FunnelStore {
+	return &FunnelStore{db: db, searchAnalytics: searchAnalytics}
+}
+
+// RecordView stores a server view, attributing it to a discovery surface
+func (s *FunnelStore) RecordView(ctx context.Context, view *model.ServerView) error {
+	view.Surface = Surface(view.Source, view.Referrer)
+
+	var position sql.NullInt64
+	if view.Position != nil {
+		position = sql.NullInt64{Int64: int64(*view.Position), Valid: true}
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO server_views (server_id, user_id, surface, source, referrer, position, search_id, session_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		view.ServerID, view.UserID, view.Surface, view.Source, view.Referrer, position, view.SearchID, view.SessionID,
+	).Scan(&view.ID, &view.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record view: %w", err)
+	}
+
+	return nil
+}
+
+// Funnel returns views, installs and conversion rates per surface in
+// [from, to), for one server or, when serverID is empty, the whole platform
+func (s *FunnelStore) Funnel(ctx context.Context, serverID string, from, to time.Time) (*model.Funnel, error) {
+	stages := make(map[string]*model.FunnelStage, len(model.Surfaces))
+	for _, surface := range model.Surfaces {
+		stages[surface] = &model.FunnelStage{Surface: surface}
+	}
+	total := &model.FunnelStage{}
+
+	if err := s.views(ctx, serverID, from, to, stages, total); err != nil {
+		return nil, err
+	}
+	if err := s.installs(ctx, serverID, from, to, stages, total); err != nil {
+		return nil, err
+	}
+
+	impressions, clicks, err := s.searchAnalytics.FunnelCounts(ctx, serverID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	search := stages[model.SurfaceSearch]
+	search.Impressions, search.Clicks = impressions, clicks
+	total.Impressions, total.Clicks = impressions, clicks
+
+	funnel := &model.Funnel{
+		ServerID: serverID,
+		From:     from,
+		To:       to,
+		Surfaces: make([]model.FunnelStage, 0, len(model.Surfaces)),
+	}
+	for _, surface := range model.Surfaces {
+		funnel.Surfaces = append(funnel.Surfaces, finalizeStage(*stages[surface]))
+	}
+	funnel.Total = finalizeStage(*total)
+
+	return funnel, nil
+}
+
+// views adds view counts per surface; the rollup row carries the totals so
+// unique viewers are not double counted across surfaces
+func (s *FunnelStore) views(ctx context.Context, serverID string, from, to time.Time, stages map[string]*model.FunnelStage, total *model.FunnelStage) error {
+	where, args := funnelFilter("created_at", serverID, from, to)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT surface, COUNT(*), COUNT(DISTINCT NULLIF(user_id, ''))
+		FROM server_views
+		WHERE `+where+`
+		GROUP BY ROLLUP (surface)`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to count views: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var surface sql.NullString
+		var views, viewers int64
+		if err := rows.Scan(&surface, &views, &viewers); err != nil {
+			return fmt.Errorf("failed to scan view counts: %w", err)
+		}
+
+		stage := total
+		if surface.Valid {
+			if stage = stages[surface.String]; stage == nil {
+				stage = stages[model.SurfaceOther]
+			}
+		}
+		stage.Views += views
+		stage.UniqueViewers += viewers
+	}
+
+	return rows.Err()
+}
+
+// installs adds install counts per surface, attributed by the source and
+// referrer sent with each install
+func (s *FunnelStore) installs(ctx context.Context, serverID string, from, to time.Time, stages map[string]*model.FunnelStage, total *model.FunnelStage) error {
+	where, args := funnelFilter("installed_at", serverID, from, to)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT source, referrer, COUNT(*)
+		FROM user_installs
+		WHERE `+where+` AND NOT flagged
+		GROUP BY source, referrer`,
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to count installs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var source, referrer string
+		var installs int64
+		if err := rows.Scan(&source, &referrer, &installs); err != nil {
+			return fmt.Errorf("failed to scan install counts: %w", err)
+		}
+
+		stages[Surface(source, referrer)].Installs += installs
+		total.Installs += installs
+	}
+
+	return rows.Err()
+}
+
+// funnelFilter builds the time range and optional server condition
+func funnelFilter(column, serverID string, from, to time.Time) (string, []interface{}) {
+	where := column + ` >= $1 AND ` + column + ` < $2`
+	args := []interface{}{from, to}
+	if serverID != "" {
+		where += ` AND server_id = $3`
+		args = append(args, serverID)
+	}
+	return where, args
+}
+
+// finalizeStage computes a stage's conversion rates
+func finalizeStage(stage model.FunnelStage) model.FunnelStage {
+	if stage.Impressions > 0 {
+		rate := roundScore(float64(stage.Clicks) / float64(stage.Impressions))
+		stage.ClickThroughRate = &rate
+	}
+	if stage.Views > 0 {
+		rate := roundScore(float64(stage.Installs) / float64(stage.Views))
+		stage.ViewToInstallRate = &rate
+	}
+	return stage
+}
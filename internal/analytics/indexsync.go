@@ -0,0 +1,284 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/realtime"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// ratingUpdateWindow is how long a server's unchanged rating is announced once
+const ratingUpdateWindow = 10 * time.Minute
+
+// usageSyncOverlap re-checks metric buckets stamped just before the previous
+// usage sync, since buckets are stamped before they are written
+const usageSyncOverlap = time.Minute
+
+// IndexSyncer propagates aggregated server statistics back into the search
+// index. Writers mark servers dirty and a scheduled flush recomputes their
+// stats in one pass, so bursts of events cost a single index update. Usage
+// counts come from the metrics rollup, whose rewritten servers are marked
+// dirty by SyncUsage. Rating changes are published to realtime subscribers.
+type IndexSyncer struct {
+	installs      *InstallStore
+	ratings       *RatingStore
+	metrics       *MetricsRollup
+	searchService *search.Service
+	hub           *realtime.Hub
+	priorWeight   int
+
+	mu         sync.Mutex
+	dirty      map[string]struct{}
+	published  map[string]model.RatingUpdate
+	mean       float64
+	meanLoaded bool
+
+	// usageSince is the bucket update time the next usage sync starts from
+	usageSince time.Time
+}
+
+// NewIndexSyncer creates a new index syncer. priorWeight is the number of
+// virtual ratings at the global mean used for weighted ratings. hub may be nil.
+func NewIndexSyncer(installs *InstallStore, ratings *RatingStore, metrics *MetricsRollup, searchService *search.Service, hub *realtime.Hub, priorWeight int) *IndexSyncer {
+	return &IndexSyncer{
+		installs:      installs,
+		ratings:       ratings,
+		metrics:       metrics,
+		searchService: searchService,
+		hub:           hub,
+		priorWeight:   priorWeight,
+		dirty:         make(map[string]struct{}),
+		published:     make(map[string]model.RatingUpdate),
+	}
+}
+
+// MarkDirty schedules a server's stats to be recomputed on the next flush
+func (s *IndexSyncer) MarkDirty(serverID string) {
+	s.mu.Lock()
+	s.dirty[serverID] = struct{}{}
+	s.mu.Unlock()
+}
+
+// Flush recomputes and writes the stats of every dirty server. Servers that
+// fail are marked dirty again for the next run.
+func (s *IndexSyncer) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.dirty
+	s.dirty = make(map[string]struct{})
+	s.mu.Unlock()
+
+	var failed int
+	for serverID := range pending {
+		if err := s.sync(ctx, serverID); err != nil {
+			log.Printf("Failed to sync stats for server %s: %v", serverID, err)
+			s.MarkDirty(serverID)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d servers failed to sync", failed, len(pending))
+	}
+
+	return nil
+}
+
+// SyncUsage marks dirty the servers whose rolled-up usage changed since the
+// last run, so the next flush writes their usage counts. The first run
+// covers every server with usage.
+func (s *IndexSyncer) SyncUsage(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	since := s.usageSince
+	s.mu.Unlock()
+
+	serverIDs, err := s.metrics.UpdatedServers(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	for _, serverID := range serverIDs {
+		s.MarkDirty(serverID)
+	}
+
+	s.mu.Lock()
+	s.usageSince = now.Add(-usageSyncOverlap)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Stats computes the current aggregated statistics for a server
+func (s *IndexSyncer) Stats(ctx context.Context, serverID string) (*model.ServerStats, error) {
+	installs, err := s.installs.Counts(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings, err := s.ratings.Summary(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	weighted, err := s.WeightedAverage(ctx, ratings.Total, ratings.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.metrics.UsageCounts(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ServerStats{
+		ServerID:         serverID,
+		InstallCount:     installs.Installs,
+		RemoveCount:      installs.Uninstalls,
+		RatingTotal:      float64(ratings.Total),
+		RatingCount:      ratings.Count,
+		RatingAverage:    ratings.Average,
+		RatingWeighted:   weighted,
+		ToolCallCount:    usage.ToolCalls,
+		PromptUseCount:   usage.PromptUses,
+		TemplateUseCount: usage.TemplateUses,
+		LastCalculated:   time.Now(),
+	}, nil
+}
+
+// WeightedAverage returns the Bayesian average of a server's ratings using
+// the global mean from the last refresh
+func (s *IndexSyncer) WeightedAverage(ctx context.Context, total, count int64) (float64, error) {
+	s.mu.Lock()
+	mean, loaded := s.mean, s.meanLoaded
+	s.mu.Unlock()
+
+	if !loaded {
+		var err error
+		if mean, err = s.refreshMean(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	return BayesianAverage(total, count, mean, s.priorWeight), nil
+}
+
+// RefreshRatingWeights reloads the global mean rating and rewrites every
+// server's weighted rating, since a shifted mean moves all of them
+func (s *IndexSyncer) RefreshRatingWeights(ctx context.Context) error {
+	mean, err := s.refreshMean(ctx)
+	if err != nil {
+		return err
+	}
+
+	totals, err := s.ratings.Totals(ctx)
+	if err != nil {
+		return err
+	}
+
+	updates := make(map[string]map[string]interface{})
+	err = s.searchService.ForEachServer(ctx, []string{"rating_weighted"}, func(server *model.ServerDetail) error {
+		summary := totals[server.ID]
+		weighted := BayesianAverage(summary.Total, summary.Count, mean, s.priorWeight)
+		if weighted == server.RatingWeighted {
+			return nil
+		}
+
+		updates[server.ID] = map[string]interface{}{"rating_weighted": weighted}
+		if len(updates) >= scoreUpdateBatch {
+			if err := s.searchService.BulkUpdateFields(ctx, updates); err != nil {
+				return err
+			}
+			updates = make(map[string]map[string]interface{})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write weighted ratings: %w", err)
+	}
+
+	if err := s.searchService.BulkUpdateFields(ctx, updates); err != nil {
+		return fmt.Errorf("failed to write weighted ratings: %w", err)
+	}
+
+	return nil
+}
+
+// refreshMean loads and caches the global mean rating
+func (s *IndexSyncer) refreshMean(ctx context.Context) (float64, error) {
+	mean, err := s.ratings.GlobalMean(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	s.mean, s.meanLoaded = mean, true
+	s.mu.Unlock()
+
+	return mean, nil
+}
+
+// sync recomputes a single server's stats and writes them to the index
+func (s *IndexSyncer) sync(ctx context.Context, serverID string) error {
+	stats, err := s.Stats(ctx, serverID)
+	if err != nil {
+		return err
+	}
+
+	err = s.searchService.UpdateServerFields(ctx, serverID, map[string]interface{}{
+		"install_count":        stats.InstallCount,
+		"active_install_count": stats.InstallCount - stats.RemoveCount,
+		"rating_average":       stats.RatingAverage,
+		"rating_count":         stats.RatingCount,
+		"rating_weighted":      stats.RatingWeighted,
+		"tool_call_count":      stats.ToolCallCount,
+		"prompt_use_count":     stats.PromptUseCount,
+		"template_use_count":   stats.TemplateUseCount,
+	})
+	if errors.Is(err, search.ErrServerNotFound) {
+		// Server left the index; nothing to update
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.publishRating(model.RatingUpdate{
+		ServerID:       serverID,
+		RatingAverage:  stats.RatingAverage,
+		RatingCount:    stats.RatingCount,
+		RatingWeighted: stats.RatingWeighted,
+	})
+
+	return nil
+}
+
+// publishRating announces a server's rating if it changed since it was last
+// announced. Installs mark servers dirty too, so most syncs leave it as is.
+func (s *IndexSyncer) publishRating(update model.RatingUpdate) {
+	if s.hub == nil {
+		return
+	}
+
+	s.mu.Lock()
+	previous, ok := s.published[update.ServerID]
+	s.published[update.ServerID] = update
+	s.mu.Unlock()
+
+	if ok && previous == update {
+		return
+	}
+	if !ok && update.RatingCount == 0 {
+		return
+	}
+
+	// Other instances syncing the same server announce the same rating
+	key := fmt.Sprintf("%s:%s:%d:%g:%g", realtime.TypeRatingUpdate, update.ServerID, update.RatingCount, update.RatingAverage, update.RatingWeighted)
+	s.hub.PublishOnce(key, ratingUpdateWindow, realtime.ChannelRatings, realtime.TypeRatingUpdate, update.ServerID, update)
+}
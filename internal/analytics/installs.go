@@ -0,0 +1,288 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+const installColumns = `id, user_id, server_id, platform, app_version, package_type, source, referrer,
+	metadata, ip_address, user_agent, flagged, installed_at, uninstalled_at, uninstall_reason, uninstall_feedback`
+
+// InstallCounts holds the aggregated install counts for a server
+type InstallCounts struct {
+	Installs       int64 `json:"installs"`
+	Uninstalls     int64 `json:"uninstalls"`
+	ActiveInstalls int64 `json:"active_installs"`
+}
+
+// InstallStore persists install and uninstall records in PostgreSQL
+type InstallStore struct {
+	db *sql.DB
+}
+
+// NewInstallStore creates a new install store
+func NewInstallStore(db *sql.DB) *InstallStore {
+	return &InstallStore{db: db}
+}
+
+// Install records an install. If the user already has an active install of
+// the server, the existing record is returned and created is false.
+func (s *InstallStore) Install(ctx context.Context, install *model.Install) (created bool, err error) {
+	metadata, err := json.Marshal(install.Metadata)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal install metadata: %w", err)
+	}
+	if install.Metadata == nil {
+		metadata = []byte("{}")
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO user_installs (user_id, server_id, platform, app_version, package_type, source, referrer, metadata,
+			ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, server_id) WHERE uninstalled_at IS NULL DO NOTHING
+		RETURNING `+installColumns,
+		install.UserID, install.ServerID, install.Platform, install.AppVersion, install.PackageType,
+		install.Source, install.Referrer, metadata, install.IPAddress, install.UserAgent,
+	)
+
+	err = scanInstall(row, install)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("failed to record install: %w", err)
+	}
+
+	// Already installed: return the active record
+	existing, err := s.Active(ctx, install.UserID, install.ServerID)
+	if err != nil {
+		return false, err
+	}
+	*install = *existing
+
+	return false, nil
+}
+
+// Uninstall closes the user's active install of a server
+func (s *InstallStore) Uninstall(ctx context.Context, userID, serverID, reason, feedback string) (*model.Install, error) {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE user_installs
+		SET uninstalled_at = NOW(), uninstall_reason = $3, uninstall_feedback = $4, updated_at = NOW()
+		WHERE user_id = $1 AND server_id = $2 AND uninstalled_at IS NULL
+		RETURNING `+installColumns,
+		userID, serverID, reason, feedback,
+	)
+
+	var install model.Install
+	if err := scanInstall(row, &install); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to record uninstall: %w", err)
+	}
+
+	return &install, nil
+}
+
+// Active returns the user's active install of a server
+func (s *InstallStore) Active(ctx context.Context, userID, serverID string) (*model.Install, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+installColumns+` FROM user_installs
+		WHERE user_id = $1 AND server_id = $2 AND uninstalled_at IS NULL`, userID, serverID)
+
+	var install model.Install
+	if err := scanInstall(row, &install); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get install: %w", err)
+	}
+
+	return &install, nil
+}
+
+// ActiveServerIDs returns the servers a user currently has installed, most
+// recent first
+func (s *InstallStore) ActiveServerIDs(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT server_id FROM user_installs
+		WHERE user_id = $1 AND uninstalled_at IS NULL
+		ORDER BY installed_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query installs: %w", err)
+	}
+	defer rows.Close()
+
+	serverIDs := []string{}
+	for rows.Next() {
+		var serverID string
+		if err := rows.Scan(&serverID); err != nil {
+			return nil, fmt.Errorf("failed to scan install: %w", err)
+		}
+		serverIDs = append(serverIDs, serverID)
+	}
+
+	return serverIDs, rows.Err()
+}
+
+// Counts aggregates the install counts for a server, excluding installs
+// flagged as fraudulent
+func (s *InstallStore) Counts(ctx context.Context, serverID string) (InstallCounts, error) {
+	var counts InstallCounts
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(uninstalled_at)
+		FROM user_installs
+		WHERE server_id = $1 AND NOT flagged`, serverID,
+	).Scan(&counts.Installs, &counts.Uninstalls)
+	if err != nil {
+		return counts, fmt.Errorf("failed to count installs: %w", err)
+	}
+
+	counts.ActiveInstalls = counts.Installs - counts.Uninstalls
+
+	return counts, nil
+}
+
+// PlatformInstallCounts summarizes installs across all servers
+type PlatformInstallCounts struct {
+	InstallCounts
+	InstallsSince int64
+	NewUsers      int64
+}
+
+// PlatformCounts aggregates unflagged installs across all servers, counting
+// installs and first-time users since the given time
+func (s *InstallStore) PlatformCounts(ctx context.Context, since time.Time) (PlatformInstallCounts, error) {
+	var counts PlatformInstallCounts
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(uninstalled_at),
+			COUNT(*) FILTER (WHERE installed_at >= $1),
+			(SELECT COUNT(*) FROM (
+				SELECT user_id FROM user_installs WHERE NOT flagged GROUP BY user_id HAVING MIN(installed_at) >= $1
+			) first_installs)
+		FROM user_installs
+		WHERE NOT flagged`, since,
+	).Scan(&counts.Installs, &counts.Uninstalls, &counts.InstallsSince, &counts.NewUsers)
+	if err != nil {
+		return counts, fmt.Errorf("failed to count platform installs: %w", err)
+	}
+
+	counts.ActiveInstalls = counts.Installs - counts.Uninstalls
+
+	return counts, nil
+}
+
+// CountsByServer aggregates the unflagged install counts of every server
+// with installs
+func (s *InstallStore) CountsByServer(ctx context.Context) (map[string]InstallCounts, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT server_id, COUNT(*), COUNT(uninstalled_at)
+		FROM user_installs
+		WHERE NOT flagged
+		GROUP BY server_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count installs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]InstallCounts)
+	for rows.Next() {
+		var serverID string
+		var c InstallCounts
+		if err := rows.Scan(&serverID, &c.Installs, &c.Uninstalls); err != nil {
+			return nil, fmt.Errorf("failed to scan install counts: %w", err)
+		}
+		c.ActiveInstalls = c.Installs - c.Uninstalls
+		counts[serverID] = c
+	}
+
+	return counts, rows.Err()
+}
+
+// CountsBetween counts the unflagged installs and uninstalls of a server
+// within [from, to)
+func (s *InstallStore) CountsBetween(ctx context.Context, serverID string, from, to time.Time) (installs, uninstalls int64, err error) {
+	err = s.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE installed_at >= $2 AND installed_at < $3),
+			COUNT(*) FILTER (WHERE uninstalled_at >= $2 AND uninstalled_at < $3)
+		FROM user_installs
+		WHERE server_id = $1 AND NOT flagged
+			AND ((installed_at >= $2 AND installed_at < $3) OR (uninstalled_at >= $2 AND uninstalled_at < $3))`,
+		serverID, from, to,
+	).Scan(&installs, &uninstalls)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count installs: %w", err)
+	}
+
+	return installs, uninstalls, nil
+}
+
+// ChangedHours returns the server hours whose install or uninstall counts
+// were affected by rows written within (since, until]
+func (s *InstallStore) ChangedHours(ctx context.Context, since, until time.Time) ([]ServerHour, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT server_id, date_trunc('hour', installed_at AT TIME ZONE 'UTC')
+		FROM user_installs
+		WHERE updated_at > $1 AND updated_at <= $2
+		UNION
+		SELECT server_id, date_trunc('hour', uninstalled_at AT TIME ZONE 'UTC')
+		FROM user_installs
+		WHERE updated_at > $1 AND updated_at <= $2 AND uninstalled_at IS NOT NULL`,
+		since, until,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed installs: %w", err)
+	}
+	defer rows.Close()
+
+	var hours []ServerHour
+	for rows.Next() {
+		var hour ServerHour
+		if err := rows.Scan(&hour.ServerID, &hour.Hour); err != nil {
+			return nil, fmt.Errorf("failed to scan changed install: %w", err)
+		}
+		hour.Hour = hour.Hour.UTC()
+		hours = append(hours, hour)
+	}
+
+	return hours, rows.Err()
+}
+
+// scanInstall scans installColumns into install
+func scanInstall(row rowScanner, install *model.Install) error {
+	var metadata []byte
+	var uninstalledAt sql.NullTime
+
+	if err := row.Scan(
+		&install.ID, &install.UserID, &install.ServerID, &install.Platform, &install.AppVersion,
+		&install.PackageType, &install.Source, &install.Referrer, &metadata, &install.IPAddress,
+		&install.UserAgent, &install.Flagged, &install.InstalledAt,
+		&uninstalledAt, &install.UninstallReason, &install.UninstallFeedback,
+	); err != nil {
+		return err
+	}
+
+	install.Metadata = nil
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &install.Metadata); err != nil {
+			return fmt.Errorf("failed to decode install metadata: %w", err)
+		}
+	}
+
+	install.UninstalledAt = nil
+	if uninstalledAt.Valid {
+		install.UninstalledAt = &uninstalledAt.Time
+	}
+
+	return nil
+}
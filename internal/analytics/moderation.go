@@ -0,0 +1,371 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Review moderation states
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+	ReviewFlagged  = "flagged"
+)
+
+// ErrInvalidTransition is returned for moderation changes the state machine forbids
+var ErrInvalidTransition = errors.New("invalid moderation transition")
+
+// reviewTransitions lists the states each moderation state may move to.
+// Edits send any review back to pending through RatingStore.Upsert.
+var reviewTransitions = map[string][]string{
+	ReviewPending:  {ReviewApproved, ReviewRejected, ReviewFlagged},
+	ReviewFlagged:  {ReviewApproved, ReviewRejected},
+	ReviewApproved: {ReviewFlagged, ReviewRejected},
+	ReviewRejected: {ReviewApproved},
+}
+
+// Moderation tuning
+const (
+	// reviewFlagThreshold is how many user flags pull an approved review into the queue
+	reviewFlagThreshold = 3
+	// minDuplicateLength ignores short texts like "great" when checking duplicates
+	minDuplicateLength = 20
+	// newAccountAge is how long after first activity an account counts as new
+	newAccountAge = 24 * time.Hour
+	// burstWindow and burstThreshold define a burst of reviews from new accounts
+	burstWindow    = time.Hour
+	burstThreshold = 5
+	// moderationBatchSize bounds the pending reviews checked per run
+	moderationBatchSize = 500
+)
+
+// canTransition reports whether a review may move between moderation states
+func canTransition(from, to string) bool {
+	for _, allowed := range reviewTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ReviewModerator runs the review moderation state machine and spam heuristics
+type ReviewModerator struct {
+	db          *sql.DB
+	indexSyncer *IndexSyncer
+}
+
+// NewReviewModerator creates a new review moderator
+func NewReviewModerator(db *sql.DB, indexSyncer *IndexSyncer) *ReviewModerator {
+	return &ReviewModerator{
+		db:          db,
+		indexSyncer: indexSyncer,
+	}
+}
+
+// Queue returns a page of reviews in the given moderation state, oldest first
+func (m *ReviewModerator) Queue(ctx context.Context, status string, offset, limit int) ([]model.Review, int, error) {
+	var total int
+	if err := m.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_ratings WHERE status = $1`, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count moderation queue: %w", err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT `+reviewColumns+` FROM user_ratings
+		WHERE status = $1
+		ORDER BY flag_count DESC, updated_at
+		LIMIT $2 OFFSET $3`, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query moderation queue: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []model.Review{}
+	for rows.Next() {
+		var review model.Review
+		if err := scanReview(rows, &review); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, total, rows.Err()
+}
+
+// Flags returns the user reports filed against a review
+func (m *ReviewModerator) Flags(ctx context.Context, ratingID int64) ([]model.ReviewFlag, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT rating_id, user_id, reason, created_at
+		FROM review_flags
+		WHERE rating_id = $1
+		ORDER BY created_at`, ratingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []model.ReviewFlag{}
+	for rows.Next() {
+		var flag model.ReviewFlag
+		if err := rows.Scan(&flag.RatingID, &flag.UserID, &flag.Reason, &flag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan review flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+
+	return flags, rows.Err()
+}
+
+// SetStatus moves a review to a new moderation state on behalf of a moderator
+func (m *ReviewModerator) SetStatus(ctx context.Context, ratingID int64, to, moderator string, reasons []string) (*model.Review, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin moderation: %w", err)
+	}
+	defer tx.Rollback()
+
+	var from string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM user_ratings WHERE id = $1 FOR UPDATE`, ratingID).Scan(&from)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+
+	if !canTransition(from, to) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+
+	if reasons == nil {
+		reasons = []string{}
+	}
+
+	var review model.Review
+	row := tx.QueryRowContext(ctx, `
+		UPDATE user_ratings
+		SET status = $2, moderation_reasons = $3, moderated_by = $4, moderated_at = NOW()
+		WHERE id = $1
+		RETURNING `+reviewColumns, ratingID, to, pq.Array(reasons), moderator)
+	if err := scanReview(row, &review); err != nil {
+		return nil, fmt.Errorf("failed to update review status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit moderation: %w", err)
+	}
+
+	m.indexSyncer.MarkDirty(review.ServerID)
+
+	return &review, nil
+}
+
+// Flag records a user's report of an approved review. Once enough users have
+// flagged it, the review leaves the public listing for the moderation queue.
+func (m *ReviewModerator) Flag(ctx context.Context, ratingID int64, userID, reason string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin flag: %w", err)
+	}
+	defer tx.Rollback()
+
+	var author, serverID, status string
+	err = tx.QueryRowContext(ctx, `SELECT user_id, server_id, status FROM user_ratings WHERE id = $1 FOR UPDATE`, ratingID).
+		Scan(&author, &serverID, &status)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && status != ReviewApproved) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get review: %w", err)
+	}
+	if author == userID {
+		return ErrOwnReview
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO review_flags (rating_id, user_id, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (rating_id, user_id) DO UPDATE SET reason = EXCLUDED.reason`,
+		ratingID, userID, reason,
+	); err != nil {
+		return fmt.Errorf("failed to record flag: %w", err)
+	}
+
+	var flagCount int
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE user_ratings
+		SET flag_count = (SELECT COUNT(*) FROM review_flags WHERE rating_id = $1)
+		WHERE id = $1
+		RETURNING flag_count`, ratingID,
+	).Scan(&flagCount); err != nil {
+		return fmt.Errorf("failed to update flag count: %w", err)
+	}
+
+	flagged := flagCount >= reviewFlagThreshold
+	if flagged {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE user_ratings
+			SET status = $2, moderation_reasons = $3, moderated_by = 'system', moderated_at = NOW()
+			WHERE id = $1`, ratingID, ReviewFlagged, pq.Array([]string{ReasonUserFlags}),
+		); err != nil {
+			return fmt.Errorf("failed to flag review: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit flag: %w", err)
+	}
+
+	if flagged {
+		m.indexSyncer.MarkDirty(serverID)
+	}
+
+	return nil
+}
+
+// RunHeuristics checks pending reviews against the spam heuristics. Clean
+// reviews are approved; suspicious ones are flagged for an admin with the
+// reasons attached. It runs as a scheduled job.
+func (m *ReviewModerator) RunHeuristics(ctx context.Context) error {
+	rows, err := m.db.QueryContext(ctx, `SELECT `+reviewColumns+` FROM user_ratings
+		WHERE status = $1
+		ORDER BY updated_at
+		LIMIT $2`, ReviewPending, moderationBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to query pending reviews: %w", err)
+	}
+
+	pending := []model.Review{}
+	for rows.Next() {
+		var review model.Review
+		if err := scanReview(rows, &review); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan review: %w", err)
+		}
+		pending = append(pending, review)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query pending reviews: %w", err)
+	}
+
+	var approved, flagged int
+	for _, review := range pending {
+		reasons, err := m.spamReasons(ctx, &review)
+		if err != nil {
+			return err
+		}
+
+		to := ReviewApproved
+		if len(reasons) > 0 {
+			to = ReviewFlagged
+		}
+
+		if _, err := m.SetStatus(ctx, review.ID, to, "system", reasons); err != nil {
+			// The review may have been edited or moderated meanwhile
+			if errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return err
+		}
+
+		if to == ReviewApproved {
+			approved++
+		} else {
+			flagged++
+		}
+	}
+
+	if approved+flagged > 0 {
+		log.Printf("Moderated reviews: %d approved, %d flagged", approved, flagged)
+	}
+
+	return nil
+}
+
+// spamReasons evaluates every spam heuristic against a review
+func (m *ReviewModerator) spamReasons(ctx context.Context, review *model.Review) ([]string, error) {
+	reasons := []string{}
+	text := strings.Join(append([]string{review.Title, review.Content}, append(review.Pros, review.Cons...)...), " ")
+
+	if len(review.Content) >= minDuplicateLength {
+		duplicate, err := m.isDuplicate(ctx, review)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate {
+			reasons = append(reasons, ReasonDuplicateContent)
+		}
+	}
+
+	if hasExcessiveLinks(text) {
+		reasons = append(reasons, ReasonLinkDensity)
+	}
+
+	burst, err := m.isNewAccountBurst(ctx, review)
+	if err != nil {
+		return nil, err
+	}
+	if burst {
+		reasons = append(reasons, ReasonNewAccountBurst)
+	}
+
+	if containsProfanity(text) {
+		reasons = append(reasons, ReasonProfanity)
+	}
+
+	return reasons, nil
+}
+
+// isDuplicate reports whether the same review text was posted on another server
+func (m *ReviewModerator) isDuplicate(ctx context.Context, review *model.Review) (bool, error) {
+	var duplicate bool
+	err := m.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_ratings
+			WHERE id <> $1
+			  AND server_id <> $2
+			  AND MD5(LOWER(BTRIM(REGEXP_REPLACE(content, '\s+', ' ', 'g')))) = MD5($3)
+		)`, review.ID, review.ServerID, normalizeReviewText(review.Content),
+	).Scan(&duplicate)
+	if err != nil {
+		return false, fmt.Errorf("failed to check duplicate reviews: %w", err)
+	}
+
+	return duplicate, nil
+}
+
+// isNewAccountBurst reports whether the review is part of a burst of reviews
+// on the same server from accounts that only just became active
+func (m *ReviewModerator) isNewAccountBurst(ctx context.Context, review *model.Review) (bool, error) {
+	var count int
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM user_ratings r
+		WHERE r.server_id = $1
+		  AND r.created_at BETWEEN $2::timestamptz - $4::interval AND $2::timestamptz + $4::interval
+		  AND r.created_at - LEAST(
+				(SELECT MIN(created_at) FROM user_ratings WHERE user_id = r.user_id),
+				COALESCE((SELECT MIN(installed_at) FROM user_installs WHERE user_id = r.user_id), r.created_at)
+			) < $3::interval`,
+		review.ServerID, review.CreatedAt, pgInterval(newAccountAge), pgInterval(burstWindow),
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check review burst: %w", err)
+	}
+
+	return count >= burstThreshold, nil
+}
+
+// pgInterval formats a duration as a PostgreSQL interval literal
+func pgInterval(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int64(d.Seconds()))
+}
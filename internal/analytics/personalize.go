@@ -0,0 +1,122 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// affinityShare is the share of a personal score from category affinity;
+// the rest comes from co-install similarity
+const affinityShare = 0.5
+
+// UserProfile summarizes a user's installs for personalization
+type UserProfile struct {
+	Installed []string
+	// Affinities maps categories to the share of the user's installs in them
+	Affinities map[string]float64
+	// Similarity maps servers to their summed Jaccard similarity to the
+	// user's installs, capped at 1
+	Similarity map[string]float64
+}
+
+// Empty reports whether the profile has no signal to personalize with
+func (p *UserProfile) Empty() bool {
+	return len(p.Affinities) == 0 && len(p.Similarity) == 0
+}
+
+// Rerank reorders servers, given in their original order, by blending that
+// order with how well each server matches the profile. weight is the share
+// of the profile in the blend. It reports whether the profile was applied.
+func (p *UserProfile) Rerank(servers []model.ServerDetail, weight float64) bool {
+	if p.Empty() {
+		return false
+	}
+
+	n := float64(len(servers))
+	scores := make(map[string]float64, len(servers))
+	for i, server := range servers {
+		var affinity float64
+		for _, category := range server.Categories {
+			affinity = math.Max(affinity, p.Affinities[category])
+		}
+		personal := affinityShare*affinity + (1-affinityShare)*p.Similarity[server.ID]
+		scores[server.ID] = (1-weight)*(1-float64(i)/n) + weight*personal
+	}
+
+	sort.SliceStable(servers, func(i, j int) bool {
+		return scores[servers[i].ID] > scores[servers[j].ID]
+	})
+
+	return true
+}
+
+// Personalizer builds user profiles from installs, their categories and the
+// co-install model
+type Personalizer struct {
+	installs      *InstallStore
+	coInstalls    *CoInstallModel
+	searchService *search.Service
+}
+
+// NewPersonalizer creates a personalizer
+func NewPersonalizer(installs *InstallStore, coInstalls *CoInstallModel, searchService *search.Service) *Personalizer {
+	return &Personalizer{
+		installs:      installs,
+		coInstalls:    coInstalls,
+		searchService: searchService,
+	}
+}
+
+// Profile builds a user's profile. Users without installs get an empty one.
+func (p *Personalizer) Profile(ctx context.Context, userID string) (*UserProfile, error) {
+	installed, err := p.installs.ActiveServerIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &UserProfile{
+		Installed:  installed,
+		Affinities: make(map[string]float64),
+		Similarity: make(map[string]float64),
+	}
+	if len(installed) == 0 {
+		return profile, nil
+	}
+
+	// Recent installs speak for the user's interests
+	sources := installed
+	if len(sources) > maxRecommendationSources {
+		sources = sources[:maxRecommendationSources]
+	}
+
+	servers, err := p.searchService.GetServers(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		for _, category := range server.Categories {
+			profile.Affinities[category]++
+		}
+	}
+	for category, count := range profile.Affinities {
+		profile.Affinities[category] = count / float64(len(servers))
+	}
+
+	similar, err := p.coInstalls.Similarities(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+	for serverID, related := range similar {
+		var similarity float64
+		for _, r := range related {
+			similarity += r.Jaccard
+		}
+		profile.Similarity[serverID] = math.Min(similarity, 1)
+	}
+
+	return profile, nil
+}
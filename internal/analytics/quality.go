@@ -0,0 +1,506 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// QualityCollection holds the latest quality report of each server
+const QualityCollection = "quality_scores"
+
+// Quality components and their share of the overall score
+const (
+	QualityCompleteness = "completeness"
+	QualityEngagement   = "engagement"
+	QualityFreshness    = "freshness"
+
+	completenessWeight = 0.4
+	engagementWeight   = 0.4
+	freshnessWeight    = 0.2
+)
+
+// Thresholds below which engagement signals are too noisy to score
+const (
+	minRetentionInstalls = 10
+	minSuccessRateEvents = 20
+	successRateDays      = 30
+	descriptionTarget    = 150
+	freshDays            = 30
+	staleDays            = 365
+)
+
+// usageOutcome counts usage events and how many of them succeeded
+type usageOutcome struct {
+	Count   int64
+	Success int64
+}
+
+// QualityScorer rates servers on metadata completeness, engagement and
+// freshness. Every report keeps its per-check sub-scores so the score can be
+// explained to publishers.
+type QualityScorer struct {
+	metrics       *mongo.Collection
+	reports       *mongo.Collection
+	installs      *InstallStore
+	searchService *search.Service
+	minRatings    int
+}
+
+// NewQualityScorer creates a quality scorer. minRatings is the number of
+// ratings at which the average rating is fully trusted.
+func NewQualityScorer(db *mongo.Database, installs *InstallStore, searchService *search.Service, minRatings int) (*QualityScorer, error) {
+	if minRatings < 1 {
+		minRatings = 1
+	}
+
+	s := &QualityScorer{
+		metrics:       db.Collection(MetricsCollection),
+		reports:       db.Collection(QualityCollection),
+		installs:      installs,
+		searchService: searchService,
+		minRatings:    minRatings,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.reports.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "server_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quality index: %w", err)
+	}
+
+	return s, nil
+}
+
+// Run scores every indexed server, storing the reports and updating the
+// index where the score changed
+func (s *QualityScorer) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	installs, err := s.installs.CountsByServer(ctx)
+	if err != nil {
+		return err
+	}
+	usage, err := s.usageOutcomes(ctx, "", now)
+	if err != nil {
+		return err
+	}
+
+	updates := make(map[string]map[string]interface{})
+	var reports []*model.QualityReport
+	var updated int
+
+	flush := func() error {
+		if err := s.searchService.BulkUpdateFields(ctx, updates); err != nil {
+			return err
+		}
+		if err := s.saveReports(ctx, reports); err != nil {
+			return err
+		}
+		updated += len(updates)
+		updates = make(map[string]map[string]interface{})
+		reports = nil
+		return nil
+	}
+
+	err = s.searchService.ForEachServer(ctx, nil, func(server *model.ServerDetail) error {
+		report := s.evaluate(server, installs[server.ID], usage[server.ID], now)
+		reports = append(reports, report)
+
+		if server.QualityScore != report.Score {
+			updates[server.ID] = map[string]interface{}{"quality_score": report.Score}
+		}
+
+		if len(reports) >= scoreUpdateBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write quality scores: %w", err)
+	}
+
+	// Drop reports of servers that left the index
+	if _, err := s.reports.DeleteMany(ctx, bson.M{"calculated_at": bson.M{"$lt": now}}); err != nil {
+		return fmt.Errorf("failed to prune quality reports: %w", err)
+	}
+
+	if updated > 0 {
+		log.Printf("Updated quality scores for %d servers", updated)
+	}
+
+	return nil
+}
+
+// Report returns a server's latest quality report, computing it on demand
+// for servers the scheduled run has not reached yet
+func (s *QualityScorer) Report(ctx context.Context, serverID string) (*model.QualityReport, error) {
+	var report model.QualityReport
+	err := s.reports.FindOne(ctx, bson.M{"server_id": serverID}).Decode(&report)
+	if err == nil {
+		return &report, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to load quality report: %w", err)
+	}
+
+	server, err := s.searchService.GetServer(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.installs.Counts(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	usage, err := s.usageOutcomes(ctx, serverID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.evaluate(server, counts, usage[serverID], now), nil
+}
+
+// evaluate scores a server from its metadata and engagement
+func (s *QualityScorer) evaluate(server *model.ServerDetail, installs InstallCounts, usage usageOutcome, now time.Time) *model.QualityReport {
+	components := []model.QualityComponent{
+		newQualityComponent(QualityCompleteness, completenessWeight, completenessChecks(server)),
+		newQualityComponent(QualityEngagement, engagementWeight, s.engagementChecks(server, installs, usage)),
+		newQualityComponent(QualityFreshness, freshnessWeight, freshnessChecks(server, now)),
+	}
+
+	// Weighted average over the components that have data
+	var total, weights float64
+	for _, component := range components {
+		if component.NoData {
+			continue
+		}
+		total += component.Score * component.Weight
+		weights += component.Weight
+	}
+
+	score := 0.0
+	if weights > 0 {
+		score = math.Round(total/weights*10) / 10
+	}
+
+	return &model.QualityReport{
+		ServerID:     server.ID,
+		Score:        score,
+		Components:   components,
+		Suggestions:  qualitySuggestions(components),
+		CalculatedAt: now,
+	}
+}
+
+// completenessChecks scores how fully the server's metadata is filled in
+func completenessChecks(server *model.ServerDetail) []model.QualityCheck {
+	description := strings.TrimSpace(server.Description)
+	capabilities := append(append(append([]model.Capability{}, server.Tools...), server.Prompts...), server.Templates...)
+
+	checks := []model.QualityCheck{
+		{
+			Name:       "description",
+			Weight:     3,
+			Score:      math.Min(float64(len(description))/descriptionTarget, 1),
+			Detail:     fmt.Sprintf("%d characters", len(description)),
+			Suggestion: fmt.Sprintf("Expand the description to at least %d characters explaining what the server does", descriptionTarget),
+		},
+		presenceCheck("license", 1, server.License, "Declare a license"),
+		presenceCheck("repository", 1.5, server.Repository, "Link the source repository"),
+		presenceCheck("homepage", 0.5, server.Homepage, "Add a homepage or documentation link"),
+		{
+			Name:       "capabilities",
+			Weight:     2,
+			Score:      boolScore(len(capabilities) > 0),
+			Detail:     fmt.Sprintf("%d tools, %d prompts, %d templates", len(server.Tools), len(server.Prompts), len(server.Templates)),
+			Suggestion: "Declare the tools, prompts and templates the server provides",
+		},
+	}
+
+	// Share of declared capabilities with a description
+	descriptions := model.QualityCheck{
+		Name:       "capability_descriptions",
+		Weight:     1.5,
+		Suggestion: "Describe every tool, prompt and template",
+	}
+	if len(capabilities) == 0 {
+		descriptions.NoData = true
+		descriptions.Detail = "no capabilities declared"
+	} else {
+		var described int
+		for _, capability := range capabilities {
+			if strings.TrimSpace(capability.Description) != "" {
+				described++
+			}
+		}
+		descriptions.Score = float64(described) / float64(len(capabilities))
+		descriptions.Detail = fmt.Sprintf("%d of %d described", described, len(capabilities))
+	}
+	checks = append(checks, descriptions)
+
+	version := model.QualityCheck{
+		Name:       "version_detail",
+		Weight:     1,
+		Score:      (boolScore(server.VersionDetail.Version != "") + boolScore(server.VersionDetail.ProtocolVersion != "")) / 2,
+		Detail:     fmt.Sprintf("version %q, protocol %q", server.VersionDetail.Version, server.VersionDetail.ProtocolVersion),
+		Suggestion: "Publish the server version and supported MCP protocol version",
+	}
+
+	var packages int
+	for _, pkg := range server.Packages {
+		if pkg.Type != "" && pkg.Name != "" {
+			packages++
+		}
+	}
+	packaged := model.QualityCheck{
+		Name:       "packages",
+		Weight:     1.5,
+		Score:      boolScore(packages > 0 || len(server.Remotes) > 0),
+		Detail:     fmt.Sprintf("%d packages, %d remotes", packages, len(server.Remotes)),
+		Suggestion: "Publish an installable package or a remote endpoint",
+	}
+
+	return append(checks, version, packaged)
+}
+
+// engagementChecks scores how users respond to the server
+func (s *QualityScorer) engagementChecks(server *model.ServerDetail, installs InstallCounts, usage usageOutcome) []model.QualityCheck {
+	// Shrink small samples towards a neutral rating
+	rating := model.QualityCheck{
+		Name:       "rating",
+		Weight:     2,
+		Suggestion: "Address the issues raised in low-rated reviews",
+	}
+	if server.RatingCount == 0 {
+		rating.NoData = true
+		rating.Detail = "no ratings yet"
+	} else {
+		confidence := math.Min(float64(server.RatingCount)/float64(s.minRatings), 1)
+		rating.Score = confidence*(server.RatingAverage-1)/4 + (1-confidence)*0.5
+		rating.Detail = fmt.Sprintf("%.2f average from %d ratings", server.RatingAverage, server.RatingCount)
+	}
+
+	retention := model.QualityCheck{
+		Name:       "retention",
+		Weight:     1.5,
+		Suggestion: "Review uninstall feedback to find why users remove the server",
+	}
+	if installs.Installs < minRetentionInstalls {
+		retention.NoData = true
+		retention.Detail = fmt.Sprintf("%d installs, %d needed", installs.Installs, minRetentionInstalls)
+	} else {
+		retention.Score = float64(installs.ActiveInstalls) / float64(installs.Installs)
+		retention.Detail = fmt.Sprintf("%d of %d installs still active", installs.ActiveInstalls, installs.Installs)
+	}
+
+	success := model.QualityCheck{
+		Name:       "tool_success_rate",
+		Weight:     1.5,
+		Suggestion: "Reduce failing tool calls and prompt uses",
+	}
+	if usage.Count < minSuccessRateEvents {
+		success.NoData = true
+		success.Detail = fmt.Sprintf("%d usage events in %d days, %d needed", usage.Count, successRateDays, minSuccessRateEvents)
+	} else {
+		success.Score = float64(usage.Success) / float64(usage.Count)
+		success.Detail = fmt.Sprintf("%d of %d calls succeeded in %d days", usage.Success, usage.Count, successRateDays)
+	}
+
+	return []model.QualityCheck{rating, retention, success}
+}
+
+// freshnessChecks scores how recently the server was updated
+func freshnessChecks(server *model.ServerDetail, now time.Time) []model.QualityCheck {
+	check := model.QualityCheck{
+		Name:       "last_updated",
+		Weight:     1,
+		Suggestion: "Publish an update; the server has not changed in a while",
+	}
+
+	if server.LastUpdated.IsZero() {
+		check.NoData = true
+		check.Detail = "update time unknown"
+		return []model.QualityCheck{check}
+	}
+
+	days := now.Sub(server.LastUpdated).Hours() / 24
+	check.Score = math.Max(0, math.Min(1, (staleDays-days)/(staleDays-freshDays)))
+	check.Detail = fmt.Sprintf("updated %d days ago", int(days))
+
+	return []model.QualityCheck{check}
+}
+
+// newQualityComponent scores a component as the weighted average of its
+// checks with data, on a 0-100 scale
+func newQualityComponent(name string, weight float64, checks []model.QualityCheck) model.QualityComponent {
+	component := model.QualityComponent{
+		Name:   name,
+		Weight: weight,
+		Checks: checks,
+	}
+
+	var total, weights float64
+	for i := range checks {
+		checks[i].Score = math.Round(checks[i].Score*1000) / 1000
+		if checks[i].NoData {
+			continue
+		}
+		total += checks[i].Score * checks[i].Weight
+		weights += checks[i].Weight
+	}
+
+	if weights == 0 {
+		component.NoData = true
+		return component
+	}
+	component.Score = math.Round(total/weights*1000) / 10
+
+	return component
+}
+
+// qualitySuggestions lists the improvements for incomplete checks, those
+// worth the most points first
+func qualitySuggestions(components []model.QualityComponent) []string {
+	type suggestion struct {
+		text  string
+		worth float64
+	}
+
+	var pending []suggestion
+	for _, component := range components {
+		var weights float64
+		for _, check := range component.Checks {
+			if !check.NoData {
+				weights += check.Weight
+			}
+		}
+
+		for _, check := range component.Checks {
+			if check.NoData || check.Score >= 1 || check.Suggestion == "" {
+				continue
+			}
+			pending = append(pending, suggestion{
+				text:  check.Suggestion,
+				worth: (1 - check.Score) * check.Weight / weights * component.Weight,
+			})
+		}
+	}
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].worth > pending[j].worth
+	})
+
+	suggestions := make([]string, len(pending))
+	for i, s := range pending {
+		suggestions[i] = s.text
+	}
+
+	return suggestions
+}
+
+// usageOutcomes sums recent usage counts per server from the daily buckets,
+// optionally for a single server
+func (s *QualityScorer) usageOutcomes(ctx context.Context, serverID string, now time.Time) (map[string]usageOutcome, error) {
+	match := bson.M{
+		"interval":     model.IntervalDay,
+		"bucket_start": bson.M{"$gte": BucketStart(model.IntervalDay, now).AddDate(0, 0, -successRateDays)},
+	}
+	if serverID != "" {
+		match["server_id"] = serverID
+	}
+
+	cursor, err := s.metrics.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$server_id",
+			"count":   bson.M{"$sum": "$usage.count"},
+			"success": bson.M{"$sum": "$usage.success_count"},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate usage outcomes: %w", err)
+	}
+
+	var results []struct {
+		ServerID string `bson:"_id"`
+		Count    int64  `bson:"count"`
+		Success  int64  `bson:"success"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode usage outcomes: %w", err)
+	}
+
+	outcomes := make(map[string]usageOutcome, len(results))
+	for _, result := range results {
+		outcomes[result.ServerID] = usageOutcome{Count: result.Count, Success: result.Success}
+	}
+
+	return outcomes, nil
+}
+
+// saveReports upserts a batch of quality reports
+func (s *QualityScorer) saveReports(ctx context.Context, reports []*model.QualityReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, len(reports))
+	for i, report := range reports {
+		writes[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"server_id": report.ServerID}).
+			SetReplacement(report).
+			SetUpsert(true)
+	}
+
+	if _, err := s.reports.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to save quality reports: %w", err)
+	}
+
+	return nil
+}
+
+// presenceCheck scores whether a metadata field is filled in
+func presenceCheck(name string, weight float64, value, suggestion string) model.QualityCheck {
+	value = strings.TrimSpace(value)
+	detail := "missing"
+	if value != "" {
+		detail = value
+	}
+
+	return model.QualityCheck{
+		Name:       name,
+		Weight:     weight,
+		Score:      boolScore(value != ""),
+		Detail:     detail,
+		Suggestion: suggestion,
+	}
+}
+
+// boolScore converts a pass/fail condition to a check score
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
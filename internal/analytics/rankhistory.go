@@ -0,0 +1,318 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// RankHistoryCollection holds one ranking snapshot per server and day
+const RankHistoryCollection = "rank_history"
+
+// moverMinShare is the share of its previous rank a server must move to
+// count as rising or falling, so moving from 500 to 495 is steady while
+// moving from 5 to 4 is not
+const moverMinShare = 0.1
+
+// Movers lists the servers whose rank changed the most over a period
+type Movers struct {
+	Ranking    string            `json:"ranking"`
+	Category   string            `json:"category,omitempty"`
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	Climbers   []model.RankMover `json:"climbers"`
+	Fallers    []model.RankMover `json:"fallers"`
+	NewEntries []model.RankMover `json:"new_entries"`
+}
+
+// snapshot records today's global and per-category ranks. Every run
+// overwrites the day's snapshot, so a finished day keeps its last ranking.
+func (s *TrendingScorer) snapshot(ctx context.Context, details []*TrendingData, categories map[string][]string, now time.Time) error {
+	day := BucketStart(model.IntervalDay, now)
+
+	snapshots := make(map[string]*model.RankSnapshot, len(details))
+	for _, data := range details {
+		snapshots[data.ServerID] = &model.RankSnapshot{
+			ServerID:        data.ServerID,
+			Day:             day,
+			TrendingScore:   data.TrendingScore,
+			PopularityScore: data.PopularityScore,
+			TrendingRank:    data.Rank,
+			GrowthRate:      data.GrowthRate,
+			Velocity:        data.Velocity,
+			Categories:      []model.CategoryRank{},
+			UpdatedAt:       now,
+		}
+	}
+
+	popularity := rankBy(details, func(d *TrendingData) float64 { return d.PopularityScore })
+	for serverID, rank := range popularity {
+		snapshots[serverID].PopularityRank = rank
+	}
+
+	// Rank within each category
+	byCategory := make(map[string][]*TrendingData)
+	for _, data := range details {
+		for _, category := range categories[data.ServerID] {
+			byCategory[category] = append(byCategory[category], data)
+		}
+	}
+	for category, members := range byCategory {
+		trending := rankBy(members, func(d *TrendingData) float64 { return d.TrendingScore })
+		popularity := rankBy(members, func(d *TrendingData) float64 { return d.PopularityScore })
+		for _, data := range members {
+			snapshot := snapshots[data.ServerID]
+			snapshot.Categories = append(snapshot.Categories, model.CategoryRank{
+				Category:       category,
+				TrendingRank:   trending[data.ServerID],
+				PopularityRank: popularity[data.ServerID],
+			})
+		}
+	}
+
+	writes := make([]mongo.WriteModel, 0, scoreUpdateBatch)
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		if _, err := s.history.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to save rank history: %w", err)
+		}
+		writes = writes[:0]
+		return nil
+	}
+	for _, snapshot := range snapshots {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"server_id": snapshot.ServerID, "day": day}).
+			SetReplacement(snapshot).
+			SetUpsert(true))
+		if len(writes) >= scoreUpdateBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	// Drop servers that fell out of today's ranking since an earlier run
+	if _, err := s.history.DeleteMany(ctx, bson.M{"day": day, "updated_at": bson.M{"$lt": now}}); err != nil {
+		return fmt.Errorf("failed to prune rank history: %w", err)
+	}
+
+	return nil
+}
+
+// RankHistory returns a server's daily snapshots since the given day, oldest first
+func (s *TrendingScorer) RankHistory(ctx context.Context, serverID string, since time.Time) ([]model.RankSnapshot, error) {
+	cursor, err := s.history.Find(ctx, bson.M{
+		"server_id": serverID,
+		"day":       bson.M{"$gte": since},
+	}, options.Find().SetSort(bson.D{{Key: "day", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rank history: %w", err)
+	}
+
+	history := []model.RankSnapshot{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, fmt.Errorf("failed to decode rank history: %w", err)
+	}
+
+	return history, nil
+}
+
+// Movers compares the latest snapshot of a ranking, globally or within a
+// category, with the one the given number of days earlier
+func (s *TrendingScorer) Movers(ctx context.Context, ranking, category string, days, limit int) (*Movers, error) {
+	to, err := s.snapshotDay(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	// Compare with the closest earlier snapshot if that day is missing
+	from, err := s.snapshotDay(ctx, bson.M{"day": bson.M{"$lte": to.AddDate(0, 0, -days)}})
+	if err == ErrNotFound {
+		from = to.AddDate(0, 0, -days)
+	} else if err != nil {
+		return nil, err
+	}
+
+	movers := &Movers{
+		Ranking:    ranking,
+		Category:   category,
+		From:       from,
+		To:         to,
+		Climbers:   []model.RankMover{},
+		Fallers:    []model.RankMover{},
+		NewEntries: []model.RankMover{},
+	}
+
+	current, err := s.dayRanks(ctx, movers.To, ranking, category)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.dayRanks(ctx, movers.From, ranking, category)
+	if err != nil {
+		return nil, err
+	}
+
+	for serverID, rank := range current {
+		previousRank := previous[serverID]
+		mover := model.RankMover{
+			ServerID:     serverID,
+			Rank:         rank,
+			PreviousRank: previousRank,
+			Velocity:     VelocityNew,
+		}
+
+		if previousRank == 0 {
+			movers.NewEntries = append(movers.NewEntries, mover)
+			continue
+		}
+
+		mover.Change = previousRank - rank
+		mover.Velocity = rankVelocity(previousRank, rank)
+		switch {
+		case mover.Change > 0:
+			movers.Climbers = append(movers.Climbers, mover)
+		case mover.Change < 0:
+			movers.Fallers = append(movers.Fallers, mover)
+		}
+	}
+
+	// Biggest moves first, ties by current rank
+	sort.Slice(movers.Climbers, func(i, j int) bool {
+		a, b := movers.Climbers[i], movers.Climbers[j]
+		if a.Change != b.Change {
+			return a.Change > b.Change
+		}
+		return a.Rank < b.Rank
+	})
+	sort.Slice(movers.Fallers, func(i, j int) bool {
+		a, b := movers.Fallers[i], movers.Fallers[j]
+		if a.Change != b.Change {
+			return a.Change < b.Change
+		}
+		return a.Rank < b.Rank
+	})
+	sort.Slice(movers.NewEntries, func(i, j int) bool {
+		return movers.NewEntries[i].Rank < movers.NewEntries[j].Rank
+	})
+
+	movers.Climbers = truncateMovers(movers.Climbers, limit)
+	movers.Fallers = truncateMovers(movers.Fallers, limit)
+	movers.NewEntries = truncateMovers(movers.NewEntries, limit)
+
+	return movers, nil
+}
+
+// snapshotDay returns the latest snapshot day matching the filter
+func (s *TrendingScorer) snapshotDay(ctx context.Context, filter bson.M) (time.Time, error) {
+	var snapshot model.RankSnapshot
+	err := s.history.FindOne(ctx, filter, options.FindOne().
+		SetSort(bson.D{{Key: "day", Value: -1}}).
+		SetProjection(bson.M{"day": 1}),
+	).Decode(&snapshot)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to find rank snapshot: %w", err)
+	}
+
+	return snapshot.Day.UTC(), nil
+}
+
+// dayRanks loads every ranked server's position on a day
+func (s *TrendingScorer) dayRanks(ctx context.Context, day time.Time, ranking, category string) (map[string]int, error) {
+	cursor, err := s.history.Find(ctx, bson.M{"day": day}, options.Find().SetProjection(bson.M{
+		"server_id": 1, "trending_rank": 1, "popularity_rank": 1, "categories": 1,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rank snapshots: %w", err)
+	}
+
+	var snapshots []model.RankSnapshot
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode rank snapshots: %w", err)
+	}
+
+	ranks := make(map[string]int, len(snapshots))
+	for _, snapshot := range snapshots {
+		if rank := snapshotRank(&snapshot, ranking, category); rank > 0 {
+			ranks[snapshot.ServerID] = rank
+		}
+	}
+
+	return ranks, nil
+}
+
+// snapshotRank returns a snapshot's rank in a ranking, globally or within a
+// category
+func snapshotRank(snapshot *model.RankSnapshot, ranking, category string) int {
+	trending, popularity := snapshot.TrendingRank, snapshot.PopularityRank
+	if category != "" {
+		trending, popularity = 0, 0
+		for _, c := range snapshot.Categories {
+			if c.Category == category {
+				trending, popularity = c.TrendingRank, c.PopularityRank
+				break
+			}
+		}
+	}
+
+	if ranking == model.RankingPopularity {
+		return popularity
+	}
+	return trending
+}
+
+// rankBy ranks the servers with a positive score, highest first
+func rankBy(details []*TrendingData, score func(*TrendingData) float64) map[string]int {
+	ranked := make([]*TrendingData, 0, len(details))
+	for _, data := range details {
+		if score(data) > 0 {
+			ranked = append(ranked, data)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return score(ranked[i]) > score(ranked[j])
+	})
+
+	ranks := make(map[string]int, len(ranked))
+	for i, data := range ranked {
+		ranks[data.ServerID] = i + 1
+	}
+	return ranks
+}
+
+// rankVelocity classifies a rank change relative to the previous rank
+func rankVelocity(previousRank, rank int) string {
+	threshold := int(math.Max(1, math.Round(float64(previousRank)*moverMinShare)))
+	change := previousRank - rank
+
+	switch {
+	case change >= threshold:
+		return VelocityRising
+	case change <= -threshold:
+		return VelocityFalling
+	default:
+		return VelocitySteady
+	}
+}
+
+// truncateMovers keeps at most limit movers
+func truncateMovers(movers []model.RankMover, limit int) []model.RankMover {
+	if len(movers) > limit {
+		return movers[:limit]
+	}
+	return movers
+}
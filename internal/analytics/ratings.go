@@ -0,0 +1,371 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// ErrOwnReview is returned when users vote on their own review
+var ErrOwnReview = errors.New("cannot vote on own review")
+
+// Review sort orders
+const (
+	ReviewSortHelpful = "helpful"
+	ReviewSortRecent  = "recent"
+	ReviewSortRating  = "rating"
+)
+
+const reviewColumns = `id, user_id, server_id, rating, title, content, pros, cons, would_recommend,
+	verified_install, helpful_count, unhelpful_count, created_at, updated_at,
+	status, moderation_reasons, flag_count, moderated_by, moderated_at`
+
+// reviewOrderBy maps review sort orders to SQL
+var reviewOrderBy = map[string]string{
+	ReviewSortHelpful: "helpful_count - unhelpful_count DESC, created_at DESC",
+	ReviewSortRecent:  "created_at DESC",
+	ReviewSortRating:  "rating DESC, created_at DESC",
+}
+
+// ReviewQuery represents parameters for listing a server's reviews
+type ReviewQuery struct {
+	Sort         string
+	VerifiedOnly bool
+	Offset       int
+	Limit        int
+}
+
+// RatingStore persists ratings and reviews in PostgreSQL
+type RatingStore struct {
+	db *sql.DB
+}
+
+// NewRatingStore creates a new rating store
+func NewRatingStore(db *sql.DB) *RatingStore {
+	return &RatingStore{db: db}
+}
+
+// Upsert creates the user's rating of a server or replaces their previous one.
+// The verified install flag is derived from the user's install records. New
+// and edited ratings go back to pending until moderation approves them.
+func (s *RatingStore) Upsert(ctx context.Context, review *model.Review) (created bool, err error) {
+	// pq encodes nil slices as NULL
+	pros, cons := review.Pros, review.Cons
+	if pros == nil {
+		pros = []string{}
+	}
+	if cons == nil {
+		cons = []string{}
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO user_ratings (user_id, server_id, rating, title, content, pros, cons, would_recommend, verified_install)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8,
+			EXISTS (SELECT 1 FROM user_installs WHERE user_id = $1 AND server_id = $2))
+		ON CONFLICT (user_id, server_id) DO UPDATE SET
+			rating = EXCLUDED.rating,
+			title = EXCLUDED.title,
+			content = EXCLUDED.content,
+			pros = EXCLUDED.pros,
+			cons = EXCLUDED.cons,
+			would_recommend = EXCLUDED.would_recommend,
+			verified_install = EXCLUDED.verified_install,
+			status = 'pending',
+			moderation_reasons = '{}',
+			moderated_by = '',
+			moderated_at = NULL,
+			updated_at = NOW()
+		RETURNING `+reviewColumns+`, (xmax = 0)`,
+		review.UserID, review.ServerID, review.Rating, review.Title, review.Content,
+		pq.Array(pros), pq.Array(cons), review.WouldRecommend,
+	)
+
+	if err := scanReview(row, review, &created); err != nil {
+		return false, fmt.Errorf("failed to save rating: %w", err)
+	}
+
+	return created, nil
+}
+
+// Get retrieves a review by ID
+func (s *RatingStore) Get(ctx context.Context, id int64) (*model.Review, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+reviewColumns+` FROM user_ratings WHERE id = $1`, id)
+
+	var review model.Review
+	if err := scanReview(row, &review); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+
+	return &review, nil
+}
+
+// List returns a page of a server's approved reviews and the total number matching
+func (s *RatingStore) List(ctx context.Context, serverID string, query ReviewQuery) ([]model.Review, int, error) {
+	orderBy, ok := reviewOrderBy[query.Sort]
+	if !ok {
+		orderBy = reviewOrderBy[ReviewSortHelpful]
+	}
+
+	where := "server_id = $1 AND status = 'approved'"
+	if query.VerifiedOnly {
+		where += " AND verified_install"
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_ratings WHERE `+where, serverID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+reviewColumns+` FROM user_ratings
+		WHERE `+where+`
+		ORDER BY `+orderBy+`
+		LIMIT $2 OFFSET $3`, serverID, query.Limit, query.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []model.Review{}
+	for rows.Next() {
+		var review model.Review
+		if err := scanReview(rows, &review); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, total, rows.Err()
+}
+
+// Summary aggregates a server's approved ratings into an average and a 1-5 star histogram
+func (s *RatingStore) Summary(ctx context.Context, serverID string) (*model.RatingSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT rating, COUNT(*)
+		FROM user_ratings
+		WHERE server_id = $1 AND status = 'approved'
+		GROUP BY rating`, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize ratings: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &model.RatingSummary{
+		Distribution: map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+	}
+
+	for rows.Next() {
+		var rating int
+		var count int64
+		if err := rows.Scan(&rating, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan rating counts: %w", err)
+		}
+		summary.Distribution[rating] = count
+		summary.Count += count
+		summary.Total += int64(rating) * count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to summarize ratings: %w", err)
+	}
+
+	if summary.Count > 0 {
+		summary.Average = float64(summary.Total) / float64(summary.Count)
+	}
+
+	return summary, nil
+}
+
+// Rating trend directions
+const (
+	RatingTrendImproving    = "improving"
+	RatingTrendDeclining    = "declining"
+	RatingTrendStable       = "stable"
+	RatingTrendInsufficient = "insufficient_data"
+)
+
+// Thresholds for calling a rating trend
+const (
+	ratingTrendMinRecent = 3
+	ratingTrendMinShift  = 0.2
+)
+
+// Trend compares the average of a server's approved ratings from the last
+// window against the ratings before it
+func (s *RatingStore) Trend(ctx context.Context, serverID string, window time.Duration) (string, error) {
+	var recentAvg, priorAvg sql.NullFloat64
+	var recentCount, priorCount int64
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			AVG(rating) FILTER (WHERE created_at >= NOW() - $2::interval),
+			COUNT(*) FILTER (WHERE created_at >= NOW() - $2::interval),
+			AVG(rating) FILTER (WHERE created_at < NOW() - $2::interval),
+			COUNT(*) FILTER (WHERE created_at < NOW() - $2::interval)
+		FROM user_ratings
+		WHERE server_id = $1 AND status = 'approved'`,
+		serverID, pgInterval(window),
+	).Scan(&recentAvg, &recentCount, &priorAvg, &priorCount)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute rating trend: %w", err)
+	}
+
+	if recentCount < ratingTrendMinRecent || priorCount == 0 {
+		return RatingTrendInsufficient, nil
+	}
+
+	switch shift := recentAvg.Float64 - priorAvg.Float64; {
+	case shift >= ratingTrendMinShift:
+		return RatingTrendImproving, nil
+	case shift <= -ratingTrendMinShift:
+		return RatingTrendDeclining, nil
+	default:
+		return RatingTrendStable, nil
+	}
+}
+
+// GlobalMean returns the average of all approved ratings
+func (s *RatingStore) GlobalMean(ctx context.Context) (float64, error) {
+	var mean sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT AVG(rating)
+		FROM user_ratings
+		WHERE status = 'approved'`,
+	).Scan(&mean)
+	if err != nil {
+		return 0, fmt.Errorf("failed to average ratings: %w", err)
+	}
+
+	return mean.Float64, nil
+}
+
+// ApprovedCount returns the number of approved ratings across all servers
+func (s *RatingStore) ApprovedCount(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM user_ratings
+		WHERE status = 'approved'`,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count ratings: %w", err)
+	}
+
+	return count, nil
+}
+
+// Totals returns the approved rating count and sum of every rated server
+func (s *RatingStore) Totals(ctx context.Context) (map[string]model.RatingSummary, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT server_id, COUNT(*), SUM(rating)
+		FROM user_ratings
+		WHERE status = 'approved'
+		GROUP BY server_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total ratings: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[string]model.RatingSummary)
+	for rows.Next() {
+		var serverID string
+		var summary model.RatingSummary
+		if err := rows.Scan(&serverID, &summary.Count, &summary.Total); err != nil {
+			return nil, fmt.Errorf("failed to scan rating totals: %w", err)
+		}
+		summary.Average = float64(summary.Total) / float64(summary.Count)
+		totals[serverID] = summary
+	}
+
+	return totals, rows.Err()
+}
+
+// BayesianAverage blends a server's ratings with priorWeight virtual ratings
+// at the global mean, so servers with few ratings rank near the mean until
+// they have enough ratings to stand on their own. Unrated servers score zero.
+func BayesianAverage(total, count int64, mean float64, priorWeight int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return (mean*float64(priorWeight) + float64(total)) / float64(int64(priorWeight)+count)
+}
+
+// Vote records whether a user found a review helpful, replacing any earlier vote
+func (s *RatingStore) Vote(ctx context.Context, ratingID int64, userID string, helpful bool) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin vote: %w", err)
+	}
+	defer tx.Rollback()
+
+	var author string
+	err = tx.QueryRowContext(ctx, `SELECT user_id FROM user_ratings WHERE id = $1 AND status = 'approved' FOR UPDATE`, ratingID).Scan(&author)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get review: %w", err)
+	}
+	if author == userID {
+		return ErrOwnReview
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO review_votes (rating_id, user_id, helpful)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (rating_id, user_id) DO UPDATE SET helpful = EXCLUDED.helpful, created_at = NOW()`,
+		ratingID, userID, helpful,
+	); err != nil {
+		return fmt.Errorf("failed to record vote: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE user_ratings SET
+			helpful_count = (SELECT COUNT(*) FROM review_votes WHERE rating_id = $1 AND helpful),
+			unhelpful_count = (SELECT COUNT(*) FROM review_votes WHERE rating_id = $1 AND NOT helpful)
+		WHERE id = $1`, ratingID,
+	); err != nil {
+		return fmt.Errorf("failed to update vote counts: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// scanReview scans reviewColumns into review, followed by any extra destinations
+func scanReview(row rowScanner, review *model.Review, extra ...interface{}) error {
+	var wouldRecommend sql.NullBool
+	var moderatedAt sql.NullTime
+	var pros, cons, reasons []string
+
+	dest := []interface{}{
+		&review.ID, &review.UserID, &review.ServerID, &review.Rating, &review.Title, &review.Content,
+		pq.Array(&pros), pq.Array(&cons), &wouldRecommend, &review.VerifiedInstall,
+		&review.HelpfulCount, &review.UnhelpfulCount, &review.CreatedAt, &review.UpdatedAt,
+		&review.Status, pq.Array(&reasons), &review.FlagCount, &review.ModeratedBy, &moderatedAt,
+	}
+
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	review.Pros = pros
+	review.Cons = cons
+	review.ModerationReasons = reasons
+	review.WouldRecommend = nil
+	if wouldRecommend.Valid {
+		review.WouldRecommend = &wouldRecommend.Bool
+	}
+	review.ModeratedAt = nil
+	if moderatedAt.Valid {
+		review.ModeratedAt = &moderatedAt.Time
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// retentionColumns counts, for each retention day, the installs old enough
+// to measure and those still installed that many days after installing
+const retentionColumns = `
+	COUNT(*) FILTER (WHERE installed_at <= NOW() - INTERVAL '1 day'),
+	COUNT(*) FILTER (WHERE installed_at <= NOW() - INTERVAL '1 day'
+		AND (uninstalled_at IS NULL OR uninstalled_at >= installed_at + INTERVAL '1 day')),
+	COUNT(*) FILTER (WHERE installed_at <= NOW() - INTERVAL '7 days'),
+	COUNT(*) FILTER (WHERE installed_at <= NOW() - INTERVAL '7 days'
+		AND (uninstalled_at IS NULL OR uninstalled_at >= installed_at + INTERVAL '7 days')),
+	COUNT(*) FILTER (WHERE installed_at <= NOW() - INTERVAL '30 days'),
+	COUNT(*) FILTER (WHERE installed_at <= NOW() - INTERVAL '30 days'
+		AND (uninstalled_at IS NULL OR uninstalled_at >= installed_at + INTERVAL '30 days'))`
+
+// Retention returns the day 1, 7 and 30 retention of all a server's installs
+func (s *InstallStore) Retention(ctx context.Context, serverID string) (model.RetentionRates, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+retentionColumns+`
+		FROM user_installs
+		WHERE server_id = $1 AND NOT flagged`, serverID)
+
+	rates, err := scanRetention(row)
+	if err != nil {
+		return rates, fmt.Errorf("failed to compute retention: %w", err)
+	}
+
+	return rates, nil
+}
+
+// Cohorts returns the retention of a server's installs grouped by install
+// week, newest first, for the given number of weeks
+func (s *InstallStore) Cohorts(ctx context.Context, serverID string, weeks int) ([]model.RetentionCohort, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT date_trunc('week', installed_at AT TIME ZONE 'UTC') AS week, COUNT(*), `+retentionColumns+`
+		FROM user_installs
+		WHERE server_id = $1 AND NOT flagged
+			AND installed_at >= date_trunc('week', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' - make_interval(weeks => $2)
+		GROUP BY week
+		ORDER BY week DESC`, serverID, weeks-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cohorts: %w", err)
+	}
+	defer rows.Close()
+
+	cohorts := []model.RetentionCohort{}
+	for rows.Next() {
+		var cohort model.RetentionCohort
+		rates, err := scanRetention(rows, &cohort.Week, &cohort.Installs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cohort: %w", err)
+		}
+		cohort.Week = cohort.Week.UTC()
+		cohort.Retention = rates
+		cohorts = append(cohorts, cohort)
+	}
+
+	return cohorts, rows.Err()
+}
+
+// UninstallReasons returns the most common reasons given for uninstalling a server
+func (s *InstallStore) UninstallReasons(ctx context.Context, serverID string, limit int) ([]model.UninstallReason, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT uninstall_reason, COUNT(*)
+		FROM user_installs
+		WHERE server_id = $1 AND NOT flagged AND uninstalled_at IS NOT NULL AND uninstall_reason <> ''
+		GROUP BY uninstall_reason
+		ORDER BY COUNT(*) DESC, uninstall_reason
+		LIMIT $2`, serverID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query uninstall reasons: %w", err)
+	}
+	defer rows.Close()
+
+	reasons := []model.UninstallReason{}
+	for rows.Next() {
+		var reason model.UninstallReason
+		if err := rows.Scan(&reason.Reason, &reason.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan uninstall reason: %w", err)
+		}
+		reasons = append(reasons, reason)
+	}
+
+	return reasons, rows.Err()
+}
+
+// scanRetention scans retentionColumns, after any leading columns, into rates
+func scanRetention(row rowScanner, leading ...interface{}) (model.RetentionRates, error) {
+	var counts [6]int64
+	dest := leading
+	for i := range counts {
+		dest = append(dest, &counts[i])
+	}
+
+	var rates model.RetentionRates
+	if err := row.Scan(dest...); err != nil {
+		return rates, err
+	}
+
+	rates.Day1 = retentionRate(counts[1], counts[0])
+	rates.Day7 = retentionRate(counts[3], counts[2])
+	rates.Day30 = retentionRate(counts[5], counts[4])
+
+	return rates, nil
+}
+
+// retentionRate returns retained/eligible, or nil when nothing is eligible
+func retentionRate(retained, eligible int64) *float64 {
+	if eligible == 0 {
+		return nil
+	}
+	rate := float64(retained) / float64(eligible)
+	return &rate
+}
@@ -0,0 +1,499 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Rollup collections
+const (
+	MetricsCollection     = "daily_metrics"
+	rollupStateCollection = "rollup_state"
+)
+
+// Watermark names, one per raw event source, plus the last hour checked
+// for usage anomalies
+const (
+	watermarkUsage    = "usage"
+	watermarkInstalls = "installs"
+	watermarkAlerts   = "alerts"
+)
+
+// ServerHour identifies one hourly bucket of a server
+type ServerHour struct {
+	ServerID string
+	Hour     time.Time
+}
+
+// MetricsRollup aggregates raw usage and install events into hourly and daily
+// metric buckets. Each run re-rolls every bucket touched by events received
+// since the previous run, so late-arriving events are folded in as well.
+type MetricsRollup struct {
+	events   *mongo.Collection
+	metrics  *mongo.Collection
+	state    *mongo.Collection
+	installs *InstallStore
+	settle   time.Duration
+}
+
+// NewMetricsRollup creates a metrics rollup. Events received within settle of
+// a run are left for the next one, giving buffered writers time to flush.
+func NewMetricsRollup(db *mongo.Database, installs *InstallStore, settle time.Duration) (*MetricsRollup, error) {
+	r := &MetricsRollup{
+		events:   db.Collection(UsageEventsCollection),
+		metrics:  db.Collection(MetricsCollection),
+		state:    db.Collection(rollupStateCollection),
+		installs: installs,
+		settle:   settle,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := r.metrics.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "server_id", Value: 1},
+				{Key: "interval", Value: 1},
+				{Key: "bucket_start", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "interval", Value: 1}, {Key: "updated_at", Value: 1}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics index: %w", err)
+	}
+
+	return r, nil
+}
+
+// Run re-rolls the hourly and daily buckets affected since the last run
+func (r *MetricsRollup) Run(ctx context.Context) error {
+	until := time.Now().UTC().Add(-r.settle)
+
+	usageSince, err := r.watermark(ctx, watermarkUsage)
+	if err != nil {
+		return err
+	}
+	installsSince, err := r.watermark(ctx, watermarkInstalls)
+	if err != nil {
+		return err
+	}
+
+	// Collect the buckets touched by new events
+	affected := make(map[ServerHour]struct{})
+
+	usageHours, err := r.changedUsageHours(ctx, usageSince, until)
+	if err != nil {
+		return err
+	}
+	installHours, err := r.installs.ChangedHours(ctx, installsSince, until)
+	if err != nil {
+		return err
+	}
+	for _, hour := range append(usageHours, installHours...) {
+		affected[hour] = struct{}{}
+	}
+
+	hours := make([]ServerHour, 0, len(affected))
+	for hour := range affected {
+		hours = append(hours, hour)
+	}
+	sort.Slice(hours, func(i, j int) bool {
+		if !hours[i].Hour.Equal(hours[j].Hour) {
+			return hours[i].Hour.Before(hours[j].Hour)
+		}
+		return hours[i].ServerID < hours[j].ServerID
+	})
+
+	// Re-roll hours, then the days containing them
+	days := make(map[ServerHour]struct{})
+	for _, hour := range hours {
+		if err := r.rollHour(ctx, hour); err != nil {
+			return err
+		}
+		days[ServerHour{ServerID: hour.ServerID, Hour: BucketStart(model.IntervalDay, hour.Hour)}] = struct{}{}
+	}
+	for day := range days {
+		if err := r.rollDay(ctx, day); err != nil {
+			return err
+		}
+	}
+
+	// Advance the watermarks only once every bucket is written
+	if err := r.setWatermark(ctx, watermarkUsage, until); err != nil {
+		return err
+	}
+	if err := r.setWatermark(ctx, watermarkInstalls, until); err != nil {
+		return err
+	}
+
+	if len(hours) > 0 {
+		log.Printf("Rolled up %d hourly and %d daily metric buckets", len(hours), len(days))
+	}
+
+	return nil
+}
+
+// Buckets returns a server's stored buckets of an interval within [from, to)
+func (r *MetricsRollup) Buckets(ctx context.Context, serverID, interval string, from, to time.Time) ([]model.MetricBucket, error) {
+	cursor, err := r.metrics.Find(ctx, bson.M{
+		"server_id":    serverID,
+		"interval":     interval,
+		"bucket_start": bson.M{"$gte": from, "$lt": to},
+	}, options.Find().SetSort(bson.D{{Key: "bucket_start", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+
+	var buckets []model.MetricBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode metrics: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// HourlyBuckets returns every server's hourly buckets starting at the given
+// hours, without latency sketches
+func (r *MetricsRollup) HourlyBuckets(ctx context.Context, hours []time.Time) ([]model.MetricBucket, error) {
+	cursor, err := r.metrics.Find(ctx, bson.M{
+		"interval":     model.IntervalHour,
+		"bucket_start": bson.M{"$in": hours},
+	}, options.Find().SetProjection(bson.M{"usage.latency": 0, "capabilities.latency": 0}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hourly metrics: %w", err)
+	}
+
+	var buckets []model.MetricBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode hourly metrics: %w", err)
+	}
+
+	return buckets, nil
+}
+
+// RolledUntil returns the receive time up to which usage has been rolled up
+func (r *MetricsRollup) RolledUntil(ctx context.Context) (time.Time, error) {
+	return r.watermark(ctx, watermarkUsage)
+}
+
+// UsageTotal sums usage events across all servers in the daily buckets since from
+func (r *MetricsRollup) UsageTotal(ctx context.Context, from time.Time) (int64, error) {
+	cursor, err := r.metrics.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"interval": model.IntervalDay, "bucket_start": bson.M{"$gte": from}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "count": bson.M{"$sum": "$usage.count"}}}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to total usage: %w", err)
+	}
+
+	var results []struct {
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return 0, fmt.Errorf("failed to decode usage total: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+
+	return results[0].Count, nil
+}
+
+// UsageCounts sums a server's usage across all of its daily buckets
+func (r *MetricsRollup) UsageCounts(ctx context.Context, serverID string) (*model.UsageMetrics, error) {
+	cursor, err := r.metrics.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"server_id": serverID, "interval": model.IntervalDay}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"count":         bson.M{"$sum": "$usage.count"},
+			"success_count": bson.M{"$sum": "$usage.success_count"},
+			"tool_calls":    bson.M{"$sum": "$usage.tool_calls"},
+			"prompt_uses":   bson.M{"$sum": "$usage.prompt_uses"},
+			"template_uses": bson.M{"$sum": "$usage.template_uses"},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count usage: %w", err)
+	}
+
+	var results []model.UsageMetrics
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode usage counts: %w", err)
+	}
+	if len(results) == 0 {
+		return &model.UsageMetrics{}, nil
+	}
+
+	return &results[0], nil
+}
+
+// UpdatedServers returns the servers whose daily buckets were rewritten after since
+func (r *MetricsRollup) UpdatedServers(ctx context.Context, since time.Time) ([]string, error) {
+	values, err := r.metrics.Distinct(ctx, "server_id", bson.M{
+		"interval":   model.IntervalDay,
+		"updated_at": bson.M{"$gt": since},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query updated metrics: %w", err)
+	}
+
+	serverIDs := make([]string, 0, len(values))
+	for _, value := range values {
+		if serverID, ok := value.(string); ok {
+			serverIDs = append(serverIDs, serverID)
+		}
+	}
+
+	return serverIDs, nil
+}
+
+// TopCapabilities returns the most used capabilities of a type across all
+// servers in the daily buckets since from
+func (r *MetricsRollup) TopCapabilities(ctx context.Context, eventType string, from time.Time, limit int) ([]model.ToolUsage, error) {
+	cursor, err := r.metrics.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"interval": model.IntervalDay, "bucket_start": bson.M{"$gte": from}}}},
+		{{Key: "$unwind", Value: "$capabilities"}},
+		{{Key: "$match", Value: bson.M{"capabilities.type": eventType}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"server_id": "$server_id", "name": "$capabilities.name"},
+			"calls":     bson.M{"$sum": "$capabilities.count"},
+			"successes": bson.M{"$sum": "$capabilities.success_count"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "calls", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"server_id": "$_id.server_id",
+			"name":      "$_id.name",
+			"calls":     1,
+			"successes": 1,
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank capabilities: %w", err)
+	}
+
+	usage := []model.ToolUsage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, fmt.Errorf("failed to decode capability ranking: %w", err)
+	}
+
+	for i := range usage {
+		if usage[i].Calls > 0 {
+			usage[i].SuccessRate = float64(usage[i].Successes) / float64(usage[i].Calls)
+		}
+	}
+
+	return usage, nil
+}
+
+// changedUsageHours returns the server hours of usage events received within (since, until]
+func (r *MetricsRollup) changedUsageHours(ctx context.Context, since, until time.Time) ([]ServerHour, error) {
+	cursor, err := r.events.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"received_at": bson.M{"$gt": since, "$lte": until}}}},
+		{{Key: "$group", Value: bson.M{"_id": bson.M{
+			"server_id": "$server_id",
+			"hour":      bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": "hour"}},
+		}}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed usage: %w", err)
+	}
+
+	var results []struct {
+		ID struct {
+			ServerID string    `bson:"server_id"`
+			Hour     time.Time `bson:"hour"`
+		} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode changed usage: %w", err)
+	}
+
+	hours := make([]ServerHour, len(results))
+	for i, result := range results {
+		hours[i] = ServerHour{ServerID: result.ID.ServerID, Hour: result.ID.Hour.UTC()}
+	}
+
+	return hours, nil
+}
+
+// rollHour recomputes one hourly bucket from the raw events
+func (r *MetricsRollup) rollHour(ctx context.Context, hour ServerHour) error {
+	start := hour.Hour
+	end := start.Add(time.Hour)
+
+	bucket := model.MetricBucket{
+		ServerID:    hour.ServerID,
+		Interval:    model.IntervalHour,
+		BucketStart: start,
+	}
+
+	cursor, err := r.events.Find(ctx, bson.M{
+		"server_id": hour.ServerID,
+		"timestamp": bson.M{"$gte": start, "$lt": end},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query usage events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	capabilities := newCapabilityMetrics()
+	for cursor.Next(ctx) {
+		var event model.UsageEvent
+		if err := cursor.Decode(&event); err != nil {
+			return fmt.Errorf("failed to decode usage event: %w", err)
+		}
+
+		bucket.Usage.Record(&event)
+		capabilities.get(event.Type, event.Name).Record(&event)
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to read usage events: %w", err)
+	}
+
+	bucket.Capabilities = capabilities.list()
+
+	bucket.Installs, bucket.Uninstalls, err = r.installs.CountsBetween(ctx, hour.ServerID, start, end)
+	if err != nil {
+		return err
+	}
+
+	return r.save(ctx, &bucket)
+}
+
+// rollDay recomputes one daily bucket by merging its hourly buckets
+func (r *MetricsRollup) rollDay(ctx context.Context, day ServerHour) error {
+	hours, err := r.Buckets(ctx, day.ServerID, model.IntervalHour, day.Hour, day.Hour.Add(24*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	bucket := model.MetricBucket{
+		ServerID:    day.ServerID,
+		Interval:    model.IntervalDay,
+		BucketStart: day.Hour,
+	}
+
+	capabilities := newCapabilityMetrics()
+	for i := range hours {
+		bucket.Usage.Merge(&hours[i].Usage)
+		bucket.Installs += hours[i].Installs
+		bucket.Uninstalls += hours[i].Uninstalls
+
+		for j := range hours[i].Capabilities {
+			capability := &hours[i].Capabilities[j]
+			capabilities.get(capability.Type, capability.Name).Merge(&capability.UsageMetrics)
+		}
+	}
+
+	bucket.Capabilities = capabilities.list()
+
+	return r.save(ctx, &bucket)
+}
+
+// save finalizes and upserts a bucket
+func (r *MetricsRollup) save(ctx context.Context, bucket *model.MetricBucket) error {
+	bucket.Usage.Finalize()
+	for i := range bucket.Capabilities {
+		bucket.Capabilities[i].Finalize()
+	}
+	bucket.UpdatedAt = time.Now().UTC()
+
+	_, err := r.metrics.ReplaceOne(ctx, bson.M{
+		"server_id":    bucket.ServerID,
+		"interval":     bucket.Interval,
+		"bucket_start": bucket.BucketStart,
+	}, bucket, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save %s bucket for server %s: %w", bucket.Interval, bucket.ServerID, err)
+	}
+
+	return nil
+}
+
+// watermark returns the receive time up to which a source has been rolled up
+func (r *MetricsRollup) watermark(ctx context.Context, name string) (time.Time, error) {
+	var state struct {
+		Watermark time.Time `bson:"watermark"`
+	}
+
+	err := r.state.FindOne(ctx, bson.M{"_id": name}).Decode(&state)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load %s watermark: %w", name, err)
+	}
+
+	return state.Watermark, nil
+}
+
+// setWatermark records the receive time up to which a source has been rolled up
+func (r *MetricsRollup) setWatermark(ctx context.Context, name string, watermark time.Time) error {
+	_, err := r.state.UpdateOne(ctx,
+		bson.M{"_id": name},
+		bson.M{"$set": bson.M{"watermark": watermark}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save %s watermark: %w", name, err)
+	}
+
+	return nil
+}
+
+// capabilityMetrics accumulates per-capability metrics in first-seen order
+type capabilityMetrics struct {
+	index map[[2]string]int
+	items []model.CapabilityMetrics
+}
+
+func newCapabilityMetrics() *capabilityMetrics {
+	return &capabilityMetrics{index: make(map[[2]string]int)}
+}
+
+// get returns the metrics of a capability, adding it if needed
+func (c *capabilityMetrics) get(eventType, name string) *model.UsageMetrics {
+	key := [2]string{eventType, name}
+	i, ok := c.index[key]
+	if !ok {
+		i = len(c.items)
+		c.index[key] = i
+		c.items = append(c.items, model.CapabilityMetrics{Type: eventType, Name: name})
+	}
+	return &c.items[i].UsageMetrics
+}
+
+// list returns the capabilities ordered by usage
+func (c *capabilityMetrics) list() []model.CapabilityMetrics {
+	items := c.items
+	if items == nil {
+		items = []model.CapabilityMetrics{}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Count > items[j].Count
+	})
+	return items
+}
+
+// BucketStart truncates t to the start of its hourly or daily bucket in UTC
+func BucketStart(interval string, t time.Time) time.Time {
+	t = t.UTC()
+	if interval == model.IntervalDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
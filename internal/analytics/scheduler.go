@@ -0,0 +1,88 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// job is a named task run on a fixed interval
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(context.Context) error
+}
+
+// Scheduler runs background jobs on fixed intervals
+type Scheduler struct {
+	jobs   []job
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewScheduler creates a new job scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registers a job that runs on start and then once per interval.
+// Jobs must be registered before Start is called.
+func (s *Scheduler) Every(name string, interval time.Duration, run func(context.Context) error) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// Start launches every registered job in its own goroutine
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+
+	log.Printf("Started %d background jobs", len(s.jobs))
+}
+
+// Stop cancels all jobs and waits for running ones to return
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// loop runs a job until the scheduler is stopped
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, j)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce runs a single job iteration bounded by the job interval
+func (s *Scheduler) runOnce(ctx context.Context, j job) {
+	runCtx, cancel := context.WithTimeout(ctx, j.interval)
+	defer cancel()
+
+	started := time.Now()
+	if err := j.run(runCtx); err != nil {
+		log.Printf("Job %s failed: %v", j.name, err)
+		return
+	}
+
+	if elapsed := time.Since(started); elapsed > j.interval/2 {
+		log.Printf("Job %s took %s (interval %s)", j.name, elapsed, j.interval)
+	}
+}
@@ -0,0 +1,503 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Search analytics collections
+const (
+	SearchQueriesCollection = "search_queries"
+	SearchEventsCollection  = "search_events"
+)
+
+// searchQueueSize bounds the searches and events waiting to be written
+const searchQueueSize = 10000
+
+// Recent searches' result pages are kept in Redis, so events can be checked
+// before the search log is written
+const (
+	searchResultsPrefix = "search:results:"
+	// searchAttributionWindow is how long after a search events on its
+	// results are accepted
+	searchAttributionWindow = 24 * time.Hour
+)
+
+// ErrInvalidSearchEvent is returned for events on results a search did not return
+var ErrInvalidSearchEvent = errors.New("server was not returned at that position by the search")
+
+// searchPage is the page of results a search returned
+type searchPage struct {
+	Offset    int      `json:"offset" bson:"offset"`
+	ResultIDs []string `json:"result_ids" bson:"result_ids"`
+}
+
+var searchWhitespace = regexp.MustCompile(`\s+`)
+
+// SearchAnalytics logs searches and the clicks and installs attributed to
+// their results. Writes are queued and batched so searches never wait on
+// MongoDB; when the queue is full, records are dropped.
+type SearchAnalytics struct {
+	queries *mongo.Collection
+	events  *mongo.Collection
+	redis   *redis.Client
+
+	queue         chan interface{}
+	batchSize     int
+	flushInterval time.Duration
+	done          chan struct{}
+	stopped       chan struct{}
+	closeOnce     sync.Once
+}
+
+// NewSearchAnalytics creates a search analytics logger and starts its writer
+func NewSearchAnalytics(db *mongo.Database, client *redis.Client, batchSize int, flushInterval time.Duration) (*SearchAnalytics, error) {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	a := &SearchAnalytics{
+		queries:       db.Collection(SearchQueriesCollection),
+		events:        db.Collection(SearchEventsCollection),
+		redis:         client,
+		queue:         make(chan interface{}, searchQueueSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := a.queries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "normalized", Value: 1}, {Key: "created_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "experiment_id", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create search query indexes: %w", err)
+	}
+
+	// One click and one install per result of a search
+	if _, err := a.events.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "search_id", Value: 1},
+				{Key: "server_id", Value: 1},
+				{Key: "type", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create search event indexes: %w", err)
+	}
+
+	go a.run()
+
+	return a, nil
+}
+
+// NormalizeQuery lowercases a query and collapses its whitespace
+func NormalizeQuery(query string) string {
+	return searchWhitespace.ReplaceAllString(strings.ToLower(strings.TrimSpace(query)), " ")
+}
+
+// LogSearch queues a search for storage and remembers its results for
+// checking events
+func (a *SearchAnalytics) LogSearch(ctx context.Context, entry *model.SearchLog) {
+	page, err := json.Marshal(searchPage{Offset: entry.Offset, ResultIDs: entry.ResultIDs})
+	if err == nil {
+		err = a.redis.Set(ctx, searchResultsPrefix+entry.ID, page, searchAttributionWindow).Err()
+	}
+	if err != nil {
+		// Events are still checked against the search log once written
+		log.Printf("Search results cache error: %v", err)
+	}
+
+	a.enqueue(entry)
+}
+
+// VerifyEvent checks that an event's server was returned by its search at
+// the event's position
+func (a *SearchAnalytics) VerifyEvent(ctx context.Context, event *model.SearchEvent) error {
+	page, err := a.resultPage(ctx, event.SearchID)
+	if err != nil {
+		return err
+	}
+
+	i := event.Position - 1 - page.Offset
+	if i < 0 || i >= len(page.ResultIDs) || page.ResultIDs[i] != event.ServerID {
+		return ErrInvalidSearchEvent
+	}
+
+	return nil
+}
+
+// RecordEvent queues a click or install attributed to a search result
+func (a *SearchAnalytics) RecordEvent(event *model.SearchEvent) {
+	a.enqueue(event)
+}
+
+// Close stops the writer after flushing queued records, waiting at most
+// until the context is done
+func (a *SearchAnalytics) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() { close(a.done) })
+
+	select {
+	case <-a.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush search analytics: %w", ctx.Err())
+	}
+}
+
+// resultPage loads the results a recent search returned, from Redis or else
+// the search log
+func (a *SearchAnalytics) resultPage(ctx context.Context, searchID string) (*searchPage, error) {
+	var page searchPage
+
+	data, err := a.redis.Get(ctx, searchResultsPrefix+searchID).Bytes()
+	if err == nil {
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode search results: %w", err)
+		}
+		return &page, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		log.Printf("Search results cache error: %v", err)
+	}
+
+	err = a.queries.FindOne(ctx, bson.M{
+		"_id":        searchID,
+		"created_at": bson.M{"$gte": time.Now().UTC().Add(-searchAttributionWindow)},
+	}, options.FindOne().SetProjection(bson.M{"offset": 1, "result_ids": 1})).Decode(&page)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidSearchEvent
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search: %w", err)
+	}
+
+	return &page, nil
+}
+
+// enqueue adds a record without blocking the caller
+func (a *SearchAnalytics) enqueue(record interface{}) {
+	select {
+	case a.queue <- record:
+	default:
+		log.Printf("Search analytics queue full, dropping %T", record)
+	}
+}
+
+// run batches queued records and writes them on size or interval
+func (a *SearchAnalytics) run() {
+	defer close(a.stopped)
+
+	ticker := time.NewTicker(a.flushInterval)
+	defer ticker.Stop()
+
+	var queries, events []interface{}
+	flush := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := a.write(ctx, a.queries, queries); err != nil {
+			log.Printf("Failed to write %d search queries: %v", len(queries), err)
+		}
+		if err := a.write(ctx, a.events, events); err != nil {
+			log.Printf("Failed to write %d search events: %v", len(events), err)
+		}
+		queries, events = nil, nil
+	}
+	add := func(record interface{}) {
+		switch record.(type) {
+		case *model.SearchLog:
+			queries = append(queries, record)
+		case *model.SearchEvent:
+			events = append(events, record)
+		}
+	}
+
+	for {
+		select {
+		case record := <-a.queue:
+			add(record)
+			if len(queries)+len(events) >= a.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-a.done:
+			// Drain what is already queued
+			for {
+				select {
+				case record := <-a.queue:
+					add(record)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// write inserts a batch, ignoring duplicate attribution events
+func (a *SearchAnalytics) write(ctx context.Context, collection *mongo.Collection, docs []interface{}) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	_, err := collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return err
+}
+
+// TopQueries returns the most frequent non-empty queries since the given
+// time, optionally only those that returned no results
+func (a *SearchAnalytics) TopQueries(ctx context.Context, since time.Time, zeroResultsOnly bool, limit int) ([]model.QueryReport, error) {
+	match := bson.M{
+		"created_at": bson.M{"$gte": since},
+		"normalized": bson.M{"$ne": ""},
+	}
+	if zeroResultsOnly {
+		match["result_count"] = 0
+	}
+
+	cursor, err := a.queries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$normalized",
+			"searches":       bson.M{"$sum": 1},
+			"zero_results":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$result_count", 0}}, 1, 0}}},
+			"avg_results":    bson.M{"$avg": "$result_count"},
+			"avg_latency_ms": bson.M{"$avg": "$latency_ms"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "searches", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate queries: %w", err)
+	}
+
+	reports := []model.QueryReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, fmt.Errorf("failed to decode query report: %w", err)
+	}
+
+	return reports, nil
+}
+
+// ClickThrough returns impressions, clicks and installs for each result
+// position up to maxPosition since the given time
+func (a *SearchAnalytics) ClickThrough(ctx context.Context, since time.Time, maxPosition int) ([]model.PositionCTR, error) {
+	// Every returned result is an impression at its absolute position
+	cursor, err := a.queries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since}}}},
+		{{Key: "$project", Value: bson.M{"position": bson.M{"$range": bson.A{
+			bson.M{"$add": bson.A{"$offset", 1}},
+			bson.M{"$min": bson.A{
+				bson.M{"$add": bson.A{"$offset", 1, bson.M{"$size": bson.M{"$ifNull": bson.A{"$result_ids", bson.A{}}}}}},
+				maxPosition + 1,
+			}},
+		}}}}},
+		{{Key: "$unwind", Value: "$position"}},
+		{{Key: "$group", Value: bson.M{"_id": "$position", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate impressions: %w", err)
+	}
+
+	var impressions []struct {
+		Position int   `bson:"_id"`
+		Count    int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &impressions); err != nil {
+		return nil, fmt.Errorf("failed to decode impressions: %w", err)
+	}
+
+	cursor, err = a.events.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"created_at": bson.M{"$gte": since},
+			"position":   bson.M{"$gte": 1, "$lte": maxPosition},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"position": "$position", "type": "$type"},
+			"count": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate search events: %w", err)
+	}
+
+	var events []struct {
+		ID struct {
+			Position int    `bson:"position"`
+			Type     string `bson:"type"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode search events: %w", err)
+	}
+
+	positions := make([]model.PositionCTR, maxPosition)
+	for i := range positions {
+		positions[i].Position = i + 1
+	}
+	for _, impression := range impressions {
+		if impression.Position >= 1 && impression.Position <= maxPosition {
+			positions[impression.Position-1].Impressions = impression.Count
+		}
+	}
+	for _, event := range events {
+		position := &positions[event.ID.Position-1]
+		switch event.ID.Type {
+		case model.SearchEventClick:
+			position.Clicks = event.Count
+		case model.SearchEventInstall:
+			position.Installs = event.Count
+		}
+	}
+
+	// Trim positions nobody has seen
+	for len(positions) > 0 && positions[len(positions)-1].Impressions == 0 {
+		positions = positions[:len(positions)-1]
+	}
+	for i := range positions {
+		if positions[i].Impressions > 0 {
+			positions[i].CTR = float64(positions[i].Clicks) / float64(positions[i].Impressions)
+			positions[i].InstallRate = float64(positions[i].Installs) / float64(positions[i].Impressions)
+		}
+	}
+
+	return positions, nil
+}
+
+// VariantSearches counts the searches an experiment variant ranked and
+// those with a result clicked
+type VariantSearches struct {
+	Searches int64 `bson:"searches"`
+	Clicked  int64 `bson:"clicked"`
+}
+
+// ExperimentSearches counts the searches of each variant of an experiment
+// logged before to, and those with a result clicked
+func (a *SearchAnalytics) ExperimentSearches(ctx context.Context, experimentID int64, to time.Time) (map[string]VariantSearches, error) {
+	cursor, err := a.queries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"experiment_id": experimentID,
+			"created_at":    bson.M{"$lt": to},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         SearchEventsCollection,
+			"localField":   "_id",
+			"foreignField": "search_id",
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"type": model.SearchEventClick}},
+				bson.M{"$limit": 1},
+			},
+			"as": "clicks",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$variant",
+			"searches": bson.M{"$sum": 1},
+			"clicked":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{bson.M{"$size": "$clicks"}, 0}}, 1, 0}}},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count experiment searches: %w", err)
+	}
+
+	var results []struct {
+		Variant         string `bson:"_id"`
+		VariantSearches `bson:",inline"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode experiment searches: %w", err)
+	}
+
+	searches := make(map[string]VariantSearches, len(results))
+	for _, result := range results {
+		searches[result.Variant] = result.VariantSearches
+	}
+
+	return searches, nil
+}
+
+// FunnelCounts returns search result impressions and clicks in [from, to),
+// for one server or, when serverID is empty, across all servers
+func (a *SearchAnalytics) FunnelCounts(ctx context.Context, serverID string, from, to time.Time) (impressions, clicks int64, err error) {
+	match := bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}
+
+	impression := bson.M{"$size": bson.M{"$ifNull": bson.A{"$result_ids", bson.A{}}}}
+	if serverID != "" {
+		match["result_ids"] = serverID
+		impression = bson.M{"$literal": 1}
+	}
+
+	cursor, err := a.queries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "count": bson.M{"$sum": impression}}}},
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count impressions: %w", err)
+	}
+
+	var totals []struct {
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return 0, 0, fmt.Errorf("failed to decode impressions: %w", err)
+	}
+	if len(totals) > 0 {
+		impressions = totals[0].Count
+	}
+
+	filter := bson.M{
+		"type":       model.SearchEventClick,
+		"created_at": bson.M{"$gte": from, "$lt": to},
+	}
+	if serverID != "" {
+		filter["server_id"] = serverID
+	}
+
+	clicks, err = a.events.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	return impressions, clicks, nil
+}
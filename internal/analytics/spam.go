@@ -0,0 +1,76 @@
+package analytics
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Spam heuristic reasons recorded on flagged reviews
+const (
+	ReasonDuplicateContent = "duplicate_content"
+	ReasonLinkDensity      = "link_density"
+	ReasonNewAccountBurst  = "new_account_burst"
+	ReasonProfanity        = "profanity"
+	ReasonUserFlags        = "user_flags"
+)
+
+// Link density limits: a review is suspicious with this many links, or when
+// links make up this share of its words
+const (
+	maxReviewLinks        = 3
+	maxReviewLinkFraction = 0.1
+)
+
+// linkPattern matches URLs and bare www. hosts
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// profanityList holds the words that send a review to manual moderation
+var profanityList = map[string]struct{}{
+	"asshole":      {},
+	"bastard":      {},
+	"bitch":        {},
+	"bullshit":     {},
+	"cunt":         {},
+	"dickhead":     {},
+	"fuck":         {},
+	"fucking":      {},
+	"motherfucker": {},
+	"shit":         {},
+	"wanker":       {},
+}
+
+// normalizeReviewText lowercases text and collapses whitespace so near-identical
+// reviews compare equal
+func normalizeReviewText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// hasExcessiveLinks reports whether text is dominated by links
+func hasExcessiveLinks(text string) bool {
+	links := len(linkPattern.FindAllString(text, -1))
+	if links == 0 {
+		return false
+	}
+	if links >= maxReviewLinks {
+		return true
+	}
+
+	words := len(strings.Fields(text))
+	return float64(links)/float64(words) > maxReviewLinkFraction
+}
+
+// containsProfanity reports whether text contains a word from the profanity list
+func containsProfanity(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for _, word := range words {
+		if _, ok := profanityList[word]; ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// Windows and sizes of the platform statistics
+const (
+	statsGrowthDays = 30
+	statsTopTools   = 50
+)
+
+// PlatformStats computes statistics across all servers by combining index
+// aggregations with install, rating, active user and usage data
+type PlatformStats struct {
+	searchService *search.Service
+	installs      *InstallStore
+	ratings       *RatingStore
+	activeUsers   *ActiveUsers
+	rollup        *MetricsRollup
+}
+
+// NewPlatformStats creates a platform statistics calculator
+func NewPlatformStats(searchService *search.Service, installs *InstallStore, ratings *RatingStore, activeUsers *ActiveUsers, rollup *MetricsRollup) *PlatformStats {
+	return &PlatformStats{
+		searchService: searchService,
+		installs:      installs,
+		ratings:       ratings,
+		activeUsers:   activeUsers,
+		rollup:        rollup,
+	}
+}
+
+// Global summarizes servers, installs, users and reviews with 30-day growth
+func (p *PlatformStats) Global(ctx context.Context) (*model.GlobalStats, error) {
+	now := time.Now().UTC()
+	since := now.AddDate(0, 0, -statsGrowthDays)
+
+	index, err := p.searchService.IndexStats(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	installs, err := p.installs.PlatformCounts(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := p.ratings.ApprovedCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := p.activeUsers.Current(ctx, "", now)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := p.rollup.UsageTotal(ctx, BucketStart(model.IntervalDay, since))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.GlobalStats{
+		TotalServers:     index.TotalServers,
+		TotalInstalls:    installs.Installs,
+		ActiveInstalls:   installs.ActiveInstalls,
+		ActiveUsers:      active.MAU,
+		DailyActiveUsers: active.DAU,
+		TotalReviews:     reviews,
+		ByCategory:       index.ByCategory,
+		ByPackageType:    index.ByPackageType,
+		ByTransport:      index.ByTransport,
+		BySource:         index.BySource,
+		Growth: model.GrowthStats{
+			Servers:     index.NewServers,
+			Installs:    installs.InstallsSince,
+			Users:       installs.NewUsers,
+			UsageEvents: usage,
+		},
+		GeneratedAt: now,
+	}, nil
+}
+
+// Categories summarizes each category
+func (p *PlatformStats) Categories(ctx context.Context) ([]model.CategoryStats, error) {
+	return p.searchService.CategoryStats(ctx, time.Now().UTC().AddDate(0, 0, -statsGrowthDays))
+}
+
+// Tools ranks tools by calls over the last 30 days and by how many servers declare them
+func (p *PlatformStats) Tools(ctx context.Context) (*model.ToolStats, error) {
+	now := time.Now().UTC()
+	since := BucketStart(model.IntervalDay, now).AddDate(0, 0, -statsGrowthDays+1)
+
+	used, err := p.rollup.TopCapabilities(ctx, model.UsageToolCall, since, statsTopTools)
+	if err != nil {
+		return nil, err
+	}
+
+	declared, err := p.searchService.ToolDeclarations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ToolStats{
+		Days:         statsGrowthDays,
+		MostUsed:     used,
+		MostDeclared: declared,
+		GeneratedAt:  now,
+	}, nil
+}
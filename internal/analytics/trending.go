@@ -0,0 +1,468 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/realtime"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// TrendingCollection holds the inputs behind each server's latest scores
+const TrendingCollection = "trending_data"
+
+// Scoring weights and windows
+const (
+	// usageWeight is the activity of a single usage event relative to an install
+	usageWeight = 0.1
+	// baselinePeriods is the length of the baseline in trending windows
+	baselinePeriods = 3
+	// maxPopularityDays bounds the history considered for popularity
+	maxPopularityDays = 365
+	// scoreUpdateBatch is the number of index updates sent per bulk request
+	scoreUpdateBatch = 500
+	// trendingUpdateSize is the number of top servers sent to realtime subscribers
+	trendingUpdateSize = 20
+	// trendingUpdateWindow is how long an unchanged top list is announced
+	// once, so instances scoring in the same period do not repeat it
+	trendingUpdateWindow = 10 * time.Minute
+)
+
+// Trending velocities, from growth of activity over the baseline
+const (
+	VelocityRising  = "rising"
+	VelocitySteady  = "steady"
+	VelocityFalling = "falling"
+	VelocityNew     = "new"
+
+	velocityMinGrowth = 0.1
+)
+
+// TrendingData records how a server's trending and popularity scores were derived
+type TrendingData struct {
+	ServerID         string    `json:"server_id" bson:"server_id"`
+	TrendingScore    float64   `json:"trending_score" bson:"trending_score"`
+	PopularityScore  float64   `json:"popularity_score" bson:"popularity_score"`
+	Rank             int       `json:"rank" bson:"rank"`
+	PreviousRank     int       `json:"previous_rank" bson:"previous_rank"`
+	RankDay          time.Time `json:"-" bson:"rank_day"`
+	GrowthRate       float64   `json:"growth_rate" bson:"growth_rate"`
+	Velocity         string    `json:"velocity" bson:"velocity"`
+	RecentInstalls   int64     `json:"recent_installs" bson:"recent_installs"`
+	RecentUsage      int64     `json:"recent_usage" bson:"recent_usage"`
+	RecentActivity   float64   `json:"recent_activity" bson:"recent_activity"`
+	ExpectedActivity float64   `json:"expected_activity" bson:"expected_activity"`
+	CalculatedAt     time.Time `json:"calculated_at" bson:"calculated_at"`
+}
+
+// TrendingScorer computes trending and popularity scores from the hourly and
+// daily metric buckets and writes them to the search index.
+//
+// Activity is installs plus weighted usage. Each bucket is decayed by
+// decayRate per day of age. The trending score compares decayed activity in
+// the trending window against what the preceding baseline predicts, so it
+// rewards acceleration rather than size. The popularity score is the decayed
+// activity over the server's whole history.
+type TrendingScorer struct {
+	metrics       *mongo.Collection
+	details       *mongo.Collection
+	history       *mongo.Collection
+	searchService *search.Service
+	hub           *realtime.Hub
+	period        time.Duration
+	minInstalls   int64
+	decayRate     float64
+}
+
+// NewTrendingScorer creates a trending scorer. Each run's top servers are
+// published to hub, which may be nil.
+func NewTrendingScorer(db *mongo.Database, searchService *search.Service, hub *realtime.Hub, periodHours, minInstalls int, decayRate float64) (*TrendingScorer, error) {
+	if periodHours < 1 {
+		periodHours = 1
+	}
+	if decayRate <= 0 || decayRate > 1 {
+		decayRate = 1
+	}
+
+	s := &TrendingScorer{
+		metrics:       db.Collection(MetricsCollection),
+		details:       db.Collection(TrendingCollection),
+		history:       db.Collection(RankHistoryCollection),
+		searchService: searchService,
+		hub:           hub,
+		period:        time.Duration(periodHours) * time.Hour,
+		minInstalls:   int64(minInstalls),
+		decayRate:     decayRate,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.details.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "server_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trending data index: %w", err)
+	}
+
+	_, err = s.history.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "server_id", Value: 1}, {Key: "day", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "day", Value: 1}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rank history indexes: %w", err)
+	}
+
+	return s, nil
+}
+
+// Run recomputes every server's scores and updates the ones that changed
+func (s *TrendingScorer) Run(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	scores, err := s.trendingInputs(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	popularity, err := s.popularity(ctx, now)
+	if err != nil {
+		return err
+	}
+	for serverID, score := range popularity {
+		data, ok := scores[serverID]
+		if !ok {
+			data = &TrendingData{ServerID: serverID}
+			scores[serverID] = data
+		}
+		data.PopularityScore = roundScore(score)
+	}
+
+	// Write changed scores, resetting servers that no longer have activity
+	updates := make(map[string]map[string]interface{})
+	var details []*TrendingData
+	categories := make(map[string][]string)
+	var updated int
+
+	err = s.searchService.ForEachServer(ctx, []string{"trending_score", "popularity_score", "categories"}, func(server *model.ServerDetail) error {
+		data, ok := scores[server.ID]
+		if !ok {
+			data = &TrendingData{ServerID: server.ID}
+		}
+		data.CalculatedAt = now
+
+		if data.TrendingScore > 0 || data.PopularityScore > 0 {
+			details = append(details, data)
+			categories[server.ID] = server.Categories
+		}
+
+		if server.TrendingScore == data.TrendingScore && server.PopularityScore == data.PopularityScore {
+			return nil
+		}
+		updates[server.ID] = map[string]interface{}{
+			"trending_score":   data.TrendingScore,
+			"popularity_score": data.PopularityScore,
+		}
+
+		if len(updates) >= scoreUpdateBatch {
+			if err := s.searchService.BulkUpdateFields(ctx, updates); err != nil {
+				return err
+			}
+			updated += len(updates)
+			updates = make(map[string]map[string]interface{})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write scores: %w", err)
+	}
+	if err := s.searchService.BulkUpdateFields(ctx, updates); err != nil {
+		return fmt.Errorf("failed to write scores: %w", err)
+	}
+	updated += len(updates)
+
+	// Rank only indexed servers, like the category ranks
+	ranks := rankBy(details, func(d *TrendingData) float64 { return d.TrendingScore })
+	for _, data := range details {
+		data.Rank = ranks[data.ServerID]
+	}
+
+	if err := s.saveDetails(ctx, details, now); err != nil {
+		return err
+	}
+	if err := s.snapshot(ctx, details, categories, now); err != nil {
+		return err
+	}
+	s.publish(details, now)
+
+	if updated > 0 {
+		log.Printf("Updated trending and popularity scores for %d servers", updated)
+	}
+
+	return nil
+}
+
+// publish sends the top trending servers to realtime subscribers
+func (s *TrendingScorer) publish(details []*TrendingData, now time.Time) {
+	if s.hub == nil {
+		return
+	}
+
+	var top []*TrendingData
+	for _, data := range details {
+		if data.Rank > 0 {
+			top = append(top, data)
+		}
+	}
+	if len(top) == 0 {
+		return
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].Rank < top[j].Rank
+	})
+	if len(top) > trendingUpdateSize {
+		top = top[:trendingUpdateSize]
+	}
+
+	ranking := make([]string, len(top))
+	for i, data := range top {
+		ranking[i] = data.ServerID
+	}
+
+	key := realtime.TypeTrendingUpdate + ":" + strings.Join(ranking, ",")
+	s.hub.PublishOnce(key, trendingUpdateWindow, realtime.ChannelTrending, realtime.TypeTrendingUpdate, "", map[string]interface{}{
+		"servers":       top,
+		"calculated_at": now,
+	})
+}
+
+// Latest returns the inputs behind a server's current scores
+func (s *TrendingScorer) Latest(ctx context.Context, serverID string) (*TrendingData, error) {
+	var data TrendingData
+	err := s.details.FindOne(ctx, bson.M{"server_id": serverID}).Decode(&data)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trending data: %w", err)
+	}
+
+	return &data, nil
+}
+
+// trendingInputs aggregates the trending window and baseline of every server
+// with recent hourly activity and derives their trending scores
+func (s *TrendingScorer) trendingInputs(ctx context.Context, now time.Time) (map[string]*TrendingData, error) {
+	windowStart := now.Add(-s.period)
+	baselineStart := windowStart.Add(-baselinePeriods * s.period)
+
+	recent := bson.M{"$gte": bson.A{"$bucket_start", windowStart}}
+	activity := bson.M{"$add": bson.A{"$installs", bson.M{"$multiply": bson.A{"$usage.count", usageWeight}}}}
+
+	cursor, err := s.metrics.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"interval":     model.IntervalHour,
+			"bucket_start": bson.M{"$gte": baselineStart},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             "$server_id",
+			"recent_installs": bson.M{"$sum": bson.M{"$cond": bson.A{recent, "$installs", 0}}},
+			"recent_usage":    bson.M{"$sum": bson.M{"$cond": bson.A{recent, "$usage.count", 0}}},
+			"recent_activity": bson.M{"$sum": bson.M{"$cond": bson.A{
+				recent, bson.M{"$multiply": bson.A{activity, s.decayExpr(now)}}, 0,
+			}}},
+			"baseline_activity": bson.M{"$sum": bson.M{"$cond": bson.A{recent, 0, activity}}},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate trending inputs: %w", err)
+	}
+
+	var results []struct {
+		ServerID         string  `bson:"_id"`
+		RecentInstalls   int64   `bson:"recent_installs"`
+		RecentUsage      int64   `bson:"recent_usage"`
+		RecentActivity   float64 `bson:"recent_activity"`
+		BaselineActivity float64 `bson:"baseline_activity"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode trending inputs: %w", err)
+	}
+
+	// Decayed weight of a full window at the baseline's hourly rate
+	var windowWeight float64
+	hours := int(s.period / time.Hour)
+	for h := 0; h < hours; h++ {
+		windowWeight += math.Pow(s.decayRate, (float64(h)+0.5)/24)
+	}
+	baselineHours := float64(baselinePeriods * hours)
+
+	scores := make(map[string]*TrendingData, len(results))
+	for _, result := range results {
+		data := &TrendingData{
+			ServerID:         result.ServerID,
+			RecentInstalls:   result.RecentInstalls,
+			RecentUsage:      result.RecentUsage,
+			RecentActivity:   roundScore(result.RecentActivity),
+			ExpectedActivity: roundScore(result.BaselineActivity / baselineHours * windowWeight),
+		}
+
+		switch {
+		case data.ExpectedActivity > 0:
+			data.GrowthRate = roundScore(result.RecentActivity/data.ExpectedActivity - 1)
+			data.Velocity = VelocitySteady
+			if data.GrowthRate >= velocityMinGrowth {
+				data.Velocity = VelocityRising
+			} else if data.GrowthRate <= -velocityMinGrowth {
+				data.Velocity = VelocityFalling
+			}
+		case result.RecentActivity > 0:
+			data.Velocity = VelocityNew
+		}
+
+		// Growth over the expectation, scaled so large servers need a
+		// proportionally larger surge
+		if data.RecentInstalls >= s.minInstalls {
+			velocity := (result.RecentActivity - data.ExpectedActivity) / math.Sqrt(data.ExpectedActivity+1)
+			data.TrendingScore = roundScore(math.Max(velocity, 0))
+		}
+
+		scores[result.ServerID] = data
+	}
+
+	return scores, nil
+}
+
+// popularity aggregates each server's decayed activity over its daily buckets
+func (s *TrendingScorer) popularity(ctx context.Context, now time.Time) (map[string]float64, error) {
+	// Ignore buckets whose weight has decayed below 1%
+	days := maxPopularityDays
+	if s.decayRate < 1 {
+		days = int(math.Min(math.Ceil(math.Log(0.01)/math.Log(s.decayRate)), maxPopularityDays))
+	}
+	since := BucketStart(model.IntervalDay, now).AddDate(0, 0, -days)
+
+	activity := bson.M{"$add": bson.A{"$installs", bson.M{"$multiply": bson.A{"$usage.count", usageWeight}}}}
+
+	cursor, err := s.metrics.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"interval":     model.IntervalDay,
+			"bucket_start": bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$server_id",
+			"score": bson.M{"$sum": bson.M{"$multiply": bson.A{activity, s.decayExpr(now)}}},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate popularity: %w", err)
+	}
+
+	var results []struct {
+		ServerID string  `bson:"_id"`
+		Score    float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode popularity: %w", err)
+	}
+
+	scores := make(map[string]float64, len(results))
+	for _, result := range results {
+		scores[result.ServerID] = result.Score
+	}
+
+	return scores, nil
+}
+
+// decayExpr is the aggregation expression for a bucket's decay weight,
+// decayRate raised to the bucket's age in days
+func (s *TrendingScorer) decayExpr(now time.Time) bson.M {
+	return bson.M{"$pow": bson.A{
+		s.decayRate,
+		bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, "$bucket_start"}}, float64(24 * time.Hour / time.Millisecond)}},
+	}}
+}
+
+// saveDetails replaces the stored score details with the latest run. The
+// previous rank carries the last rank of the prior day.
+func (s *TrendingScorer) saveDetails(ctx context.Context, details []*TrendingData, now time.Time) error {
+	previous, err := s.storedRanks(ctx)
+	if err != nil {
+		return err
+	}
+
+	today := BucketStart(model.IntervalDay, now)
+	for _, data := range details {
+		if old, ok := previous[data.ServerID]; ok {
+			if old.RankDay.Before(today) {
+				data.PreviousRank = old.Rank
+			} else {
+				data.PreviousRank = old.PreviousRank
+			}
+		}
+		data.RankDay = today
+	}
+
+	if len(details) > 0 {
+		writes := make([]mongo.WriteModel, len(details))
+		for i, data := range details {
+			writes[i] = mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"server_id": data.ServerID}).
+				SetReplacement(data).
+				SetUpsert(true)
+		}
+
+		if _, err := s.details.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return fmt.Errorf("failed to save trending data: %w", err)
+		}
+	}
+
+	// Drop servers that no longer have any score
+	if _, err := s.details.DeleteMany(ctx, bson.M{"calculated_at": bson.M{"$lt": now}}); err != nil {
+		return fmt.Errorf("failed to prune trending data: %w", err)
+	}
+
+	return nil
+}
+
+// storedRanks loads the ranks saved by the previous run
+func (s *TrendingScorer) storedRanks(ctx context.Context) (map[string]TrendingData, error) {
+	cursor, err := s.details.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{
+		"server_id": 1, "rank": 1, "previous_rank": 1, "rank_day": 1,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trending ranks: %w", err)
+	}
+
+	var stored []TrendingData
+	if err := cursor.All(ctx, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode trending ranks: %w", err)
+	}
+
+	ranks := make(map[string]TrendingData, len(stored))
+	for _, data := range stored {
+		ranks[data.ServerID] = data
+	}
+
+	return ranks, nil
+}
+
+// roundScore rounds a score to four decimals so unchanged scores compare equal
+func roundScore(score float64) float64 {
+	return math.Round(score*1e4) / 1e4
+}
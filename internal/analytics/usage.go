@@ -0,0 +1,225 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// UsageEventsCollection holds the raw usage events as received
+const UsageEventsCollection = "usage_events"
+
+// ErrUsageBufferFull is returned when events arrive faster than they can be written
+var ErrUsageBufferFull = errors.New("usage buffer full")
+
+// UsageBuffer collects usage events in memory and writes them to MongoDB in
+// batches, either when a batch fills up or when the flush interval elapses.
+type UsageBuffer struct {
+	collection    *mongo.Collection
+	batchSize     int
+	flushInterval time.Duration
+
+	mu      sync.Mutex
+	pending []model.UsageEvent
+	closed  bool
+
+	flushNow chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewUsageBuffer creates a usage buffer and starts its flush loop
+func NewUsageBuffer(db *mongo.Database, batchSize int, flushInterval time.Duration) (*UsageBuffer, error) {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	b := &UsageBuffer{
+		collection:    db.Collection(UsageEventsCollection),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		flushNow:      make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := b.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	go b.run()
+
+	return b, nil
+}
+
+// Add queues events for the next flush. Events are rejected as a whole when
+// the buffer already holds more than its capacity.
+func (b *UsageBuffer) Add(events ...model.UsageEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrUsageBufferFull
+	}
+	if len(b.pending)+len(events) > b.capacity() {
+		return ErrUsageBufferFull
+	}
+
+	// IDs are assigned once, so retried batches do not duplicate events
+	// that were already written
+	for i := range events {
+		if events[i].ID.IsZero() {
+			events[i].ID = primitive.NewObjectID()
+		}
+	}
+	b.pending = append(b.pending, events...)
+
+	// Wake the flush loop once a full batch is ready
+	if len(b.pending) >= b.batchSize {
+		select {
+		case b.flushNow <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Close stops the flush loop and writes any remaining events
+func (b *UsageBuffer) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.done)
+	<-b.stopped
+
+	return b.Flush(ctx)
+}
+
+// Flush writes all buffered events in batches. Events from a failed batch are
+// put back so the next flush retries them.
+func (b *UsageBuffer) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	events := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	for start := 0; start < len(events); start += b.batchSize {
+		end := start + b.batchSize
+		if end > len(events) {
+			end = len(events)
+		}
+
+		if err := b.write(ctx, events[start:end]); err != nil {
+			b.requeue(events[start:])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run flushes on every interval tick and whenever a batch fills up
+func (b *UsageBuffer) run() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		case <-b.flushNow:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := b.Flush(ctx); err != nil {
+			log.Printf("Usage flush error: %v", err)
+		}
+		cancel()
+	}
+}
+
+// write inserts one batch of events, ignoring events a previous attempt
+// already wrote. Events are stamped as received when they are written, so
+// ones retried after an outage are still picked up by the rollup.
+func (b *UsageBuffer) write(ctx context.Context, events []model.UsageEvent) error {
+	now := time.Now().UTC()
+
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		events[i].ReceivedAt = now
+		docs[i] = events[i]
+	}
+
+	_, err := b.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return fmt.Errorf("failed to insert usage events: %w", err)
+			}
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert usage events: %w", err)
+	}
+
+	return nil
+}
+
+// requeue puts unwritten events back at the front of the buffer, dropping the
+// oldest ones when the buffer would exceed its capacity
+func (b *UsageBuffer) requeue(events []model.UsageEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	merged := append(append([]model.UsageEvent{}, events...), b.pending...)
+	if excess := len(merged) - b.capacity(); excess > 0 {
+		log.Printf("Usage buffer over capacity, dropping %d events", excess)
+		merged = merged[excess:]
+	}
+	b.pending = merged
+}
+
+// capacity is the maximum number of events held in memory
+func (b *UsageBuffer) capacity() int {
+	return b.batchSize * 100
+}
+
+// ensureIndexes creates the indexes used by rollups and lookups
+func (b *UsageBuffer) ensureIndexes(ctx context.Context) error {
+	_, err := b.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "timestamp", Value: 1}}},
+		{Keys: bson.D{{Key: "received_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create usage event indexes: %w", err)
+	}
+
+	return nil
+}
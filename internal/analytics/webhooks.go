@@ -0,0 +1,369 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Webhook delivery tuning
+const (
+	// maxWebhookFailures consecutive failed deliveries deactivate a webhook
+	maxWebhookFailures = 10
+	// maxWebhooksPerUser bounds the webhooks a user may register
+	maxWebhooksPerUser = 20
+	webhookTimeout     = 5 * time.Second
+	// webhookWorkers deliver queued notifications concurrently, so slow
+	// endpoints hold up only their own deliveries
+	webhookWorkers   = 4
+	webhookQueueSize = 1000
+)
+
+// Webhook errors
+var (
+	ErrWebhookLimit     = errors.New("webhook limit reached")
+	ErrWebhookDuplicate = errors.New("webhook already registered")
+)
+
+// errPrivateAddress is returned when a webhook resolves to a private address
+var errPrivateAddress = errors.New("webhook address is not public")
+
+const webhookColumns = `id, user_id, server_id, url, active, failure_count, last_error, last_delivery_at, created_at`
+
+// webhookDelivery is a notification queued for one webhook
+type webhookDelivery struct {
+	id     int64
+	url    string
+	secret string
+	event  string
+	body   []byte
+}
+
+// WebhookStore persists alert webhooks and delivers signed notifications.
+// Notifications are queued and delivered by a fixed pool of workers, so
+// callers never wait on webhook endpoints.
+type WebhookStore struct {
+	db     *sql.DB
+	client *http.Client
+
+	queue     chan webhookDelivery
+	workers   sync.WaitGroup
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWebhookStore creates a webhook store and starts its delivery workers.
+// Unless allowPrivate is set, deliveries to loopback, private and link-local
+// addresses are refused.
+func NewWebhookStore(db *sql.DB, allowPrivate bool) *WebhookStore {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	s := &WebhookStore{
+		db: db,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: webhookTimeout,
+			},
+			// Redirects could point anywhere; treat them as failures
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		queue:   make(chan webhookDelivery, webhookQueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	s.workers.Add(webhookWorkers)
+	for i := 0; i < webhookWorkers; i++ {
+		go s.work()
+	}
+	go func() {
+		s.workers.Wait()
+		close(s.stopped)
+	}()
+
+	return s
+}
+
+// Close stops the workers after delivering queued notifications, waiting at
+// most until the context is done
+func (s *WebhookStore) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() { close(s.done) })
+
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to deliver queued webhooks: %w", ctx.Err())
+	}
+}
+
+// Create registers a webhook with a fresh signing secret
+func (s *WebhookStore) Create(ctx context.Context, webhook *model.AlertWebhook) error {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM alert_webhooks WHERE user_id = $1`,
+		webhook.UserID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= maxWebhooksPerUser {
+		return ErrWebhookLimit
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	webhook.Secret = hex.EncodeToString(secret)
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO alert_webhooks (user_id, server_id, url, secret)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, server_id, url) DO NOTHING
+		RETURNING `+webhookColumns,
+		webhook.UserID, webhook.ServerID, webhook.URL, webhook.Secret,
+	)
+	err := scanWebhook(row, webhook)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// List returns a user's webhooks
+func (s *WebhookStore) List(ctx context.Context, userID string) ([]model.AlertWebhook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM alert_webhooks
+		WHERE user_id = $1
+		ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []model.AlertWebhook{}
+	for rows.Next() {
+		var webhook model.AlertWebhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// Delete removes one of a user's webhooks
+func (s *WebhookStore) Delete(ctx context.Context, id int64, userID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM alert_webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Notify queues an alert event for every active webhook of the alert's
+// server. Delivery failures are recorded on the webhook; notifications that
+// do not fit in the queue are dropped.
+func (s *WebhookStore) Notify(ctx context.Context, event string, alert *model.UsageAlert) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, url, secret FROM alert_webhooks
+		WHERE server_id = $1 AND active`, alert.ServerID)
+	if err != nil {
+		return fmt.Errorf("failed to query webhooks: %w", err)
+	}
+
+	type target struct {
+		id     int64
+		url    string
+		secret string
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.id, &t.url, &t.secret); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"event":   event,
+		"alert":   alert,
+		"sent_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	for _, t := range targets {
+		select {
+		case s.queue <- webhookDelivery{id: t.id, url: t.url, secret: t.secret, event: event, body: body}:
+		default:
+			log.Printf("Webhook queue full, dropping %s for webhook %d", event, t.id)
+		}
+	}
+
+	return nil
+}
+
+// work delivers queued notifications until the store is closed, then
+// delivers what is already queued
+func (s *WebhookStore) work() {
+	defer s.workers.Done()
+
+	for {
+		select {
+		case d := <-s.queue:
+			s.send(d)
+		case <-s.done:
+			for {
+				select {
+				case d := <-s.queue:
+					s.send(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// send delivers one notification and records the outcome
+func (s *WebhookStore) send(d webhookDelivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*webhookTimeout)
+	defer cancel()
+
+	deliveryErr := s.deliver(ctx, d.url, d.secret, d.event, d.body)
+	if deliveryErr != nil {
+		log.Printf("Webhook %d delivery failed: %v", d.id, deliveryErr)
+	}
+	if err := s.recordDelivery(ctx, d.id, deliveryErr); err != nil {
+		log.Printf("Alert webhook error: %v", err)
+	}
+}
+
+// deliver posts a payload signed with HMAC-SHA256 of the webhook secret
+func (s *WebhookStore) deliver(ctx context.Context, url, secret, event string, body []byte) error {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Analytics-Event", event)
+	req.Header.Set("X-Analytics-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// recordDelivery tracks consecutive failures, deactivating the webhook once
+// there are too many
+func (s *WebhookStore) recordDelivery(ctx context.Context, id int64, deliveryErr error) error {
+	var err error
+	if deliveryErr == nil {
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE alert_webhooks
+			SET failure_count = 0, last_error = '', last_delivery_at = NOW()
+			WHERE id = $1`, id)
+	} else {
+		_, err = s.db.ExecContext(ctx, `
+			UPDATE alert_webhooks
+			SET failure_count = failure_count + 1, last_error = $2,
+				active = failure_count + 1 < $3
+			WHERE id = $1`, id, truncateError(deliveryErr), maxWebhookFailures)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// truncateError limits stored delivery errors
+func truncateError(err error) string {
+	message := err.Error()
+	if len(message) > 500 {
+		message = strings.ToValidUTF8(message[:500], "")
+	}
+	return message
+}
+
+// scanWebhook scans webhookColumns into webhook
+func scanWebhook(row rowScanner, webhook *model.AlertWebhook) error {
+	var lastDelivery sql.NullTime
+
+	if err := row.Scan(
+		&webhook.ID, &webhook.UserID, &webhook.ServerID, &webhook.URL, &webhook.Active,
+		&webhook.FailureCount, &webhook.LastError, &lastDelivery, &webhook.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	webhook.LastDeliveryAt = nil
+	if lastDelivery.Valid {
+		webhook.LastDeliveryAt = &lastDelivery.Time
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/config"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// maxWebhookURLLength limits registered webhook URLs
+const maxWebhookURLLength = 2048
+
+// AlertHandler serves usage anomaly alerts and their webhooks
+type AlertHandler struct {
+	detector      *analytics.AnomalyDetector
+	webhooks      *analytics.WebhookStore
+	searchService *search.Service
+	cfg           *config.Config
+}
+
+// webhookRequest is the payload for POST /v1/servers/{id}/webhooks
+type webhookRequest struct {
+	URL string `json:"url"`
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(detector *analytics.AnomalyDetector, webhooks *analytics.WebhookStore, searchService *search.Service, cfg *config.Config) *AlertHandler {
+	return &AlertHandler{
+		detector:      detector,
+		webhooks:      webhooks,
+		searchService: searchService,
+		cfg:           cfg,
+	}
+}
+
+// ServerAlerts lists a server's usage alerts (open by default)
+func (h *AlertHandler) ServerAlerts(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, serverID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	return h.list(ctx, c, serverID)
+}
+
+// Alerts lists usage alerts across all servers (open by default)
+func (h *AlertHandler) Alerts(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	return h.list(ctx, c, "")
+}
+
+// CreateWebhook registers a webhook notified of a server's alerts. The
+// signing secret is only returned here. Any user may subscribe to any server:
+// alerts are public on /v1/servers/:id/alerts and webhooks deliver the same
+// payload, and servers record no owner to restrict them to.
+func (h *AlertHandler) CreateWebhook(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var req webhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := h.validateWebhookURL(req.URL); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, serverID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	webhook := &model.AlertWebhook{
+		UserID:   currentUser(c).UserID(),
+		ServerID: serverID,
+		URL:      req.URL,
+	}
+
+	err = h.webhooks.Create(ctx, webhook)
+	switch {
+	case errors.Is(err, analytics.ErrWebhookLimit):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Webhook limit reached",
+		})
+	case errors.Is(err, analytics.ErrWebhookDuplicate):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Webhook already registered",
+		})
+	case err != nil:
+		log.Printf("Webhook create error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create webhook",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(webhook)
+}
+
+// Webhooks lists the authenticated user's webhooks
+func (h *AlertHandler) Webhooks(c *fiber.Ctx) error {
+	webhooks, err := h.webhooks.List(c.Context(), currentUser(c).UserID())
+	if err != nil {
+		log.Printf("Webhook list error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load webhooks",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"webhooks": webhooks,
+	})
+}
+
+// DeleteWebhook removes one of the authenticated user's webhooks
+func (h *AlertHandler) DeleteWebhook(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid webhook ID",
+		})
+	}
+
+	err = h.webhooks.Delete(c.Context(), int64(id), currentUser(c).UserID())
+	switch {
+	case errors.Is(err, analytics.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	case err != nil:
+		log.Printf("Webhook delete error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete webhook",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// list serves a page of alerts filtered by the status query parameter
+func (h *AlertHandler) list(ctx context.Context, c *fiber.Ctx, serverID string) error {
+	status := c.Query("status", model.AlertOpen)
+	switch status {
+	case model.AlertOpen, model.AlertResolved:
+	case "all":
+		status = ""
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be one of open, resolved, all",
+		})
+	}
+
+	page, limit := pageParams(c, 50, 200)
+
+	alerts, total, err := h.detector.List(ctx, serverID, status, (page-1)*limit, limit)
+	if err != nil {
+		log.Printf("Alert list error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load alerts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"alerts":     alerts,
+		"pagination": newPagination(page, limit, int(total)),
+	})
+}
+
+// validateWebhookURL requires an absolute HTTPS URL; plain HTTP is allowed
+// in development
+func (h *AlertHandler) validateWebhookURL(raw string) error {
+	if raw == "" || len(raw) > maxWebhookURLLength {
+		return errors.New("url is required and must be at most 2048 characters")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return errors.New("url must be an absolute URL")
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "https" && !(scheme == "http" && h.cfg.IsDevelopment()) {
+		return errors.New("url must use https")
+	}
+
+	return nil
+}
@@ -0,0 +1,352 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/cache"
+	"github.com/pluggedin/mcp-analytics/internal/config"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// Sizes and windows of the breakdowns in the analytics response
+const (
+	cohortWeeks         = 12
+	topUninstallReasons = 5
+	usageWindowDays     = 30
+	ratingTrendWindow   = 30 * 24 * time.Hour
+)
+
+// Section statuses of the analytics response
+const (
+	sectionOK          = "ok"
+	sectionUnavailable = "unavailable"
+)
+
+// analyticsCachePrefix prefixes every cached server analytics response
+const analyticsCachePrefix = "analytics:server:"
+
+// AnalyticsHandler serves the per-server analytics summary
+type AnalyticsHandler struct {
+	installs      *analytics.InstallStore
+	ratings       *analytics.RatingStore
+	indexSyncer   *analytics.IndexSyncer
+	activeUsers   *analytics.ActiveUsers
+	rollup        *analytics.MetricsRollup
+	trending      *analytics.TrendingScorer
+	searchService *search.Service
+	cache         *cache.Cache
+	cfg           *config.Config
+}
+
+// serverAnalytics is the analytics response. Sections whose backing store
+// failed are null and marked unavailable in Status.
+type serverAnalytics struct {
+	ServerID    string            `json:"server_id"`
+	Metrics     *serverMetrics    `json:"metrics"`
+	Ratings     *ratingAnalytics  `json:"ratings"`
+	Usage       fiber.Map         `json:"usage"`
+	Trending    *trendingSection  `json:"trending"`
+	Status      map[string]string `json:"status"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// serverMetrics is the metrics section of the analytics response
+type serverMetrics struct {
+	TotalInstalls      int64                   `json:"total_installs"`
+	ActiveInstalls     int64                   `json:"active_installs"`
+	DailyActiveUsers   int64                   `json:"daily_active_users"`
+	WeeklyActiveUsers  int64                   `json:"weekly_active_users"`
+	MonthlyActiveUsers int64                   `json:"monthly_active_users"`
+	UninstallRate      float64                 `json:"uninstall_rate"`
+	RetentionRate      model.RetentionRates    `json:"retention_rate"`
+	Cohorts            []model.RetentionCohort `json:"cohorts"`
+	UninstallReasons   []model.UninstallReason `json:"top_uninstall_reasons"`
+}
+
+// ratingAnalytics is the ratings section of the analytics response
+type ratingAnalytics struct {
+	*model.RatingSummary
+	RecentTrend string `json:"recent_trend"`
+}
+
+// trendingSection is the trending section of the analytics response
+type trendingSection struct {
+	Rank            *int    `json:"rank"`
+	PreviousRank    *int    `json:"previous_rank"`
+	GrowthRate      float64 `json:"growth_rate"`
+	Velocity        string  `json:"velocity"`
+	TrendingScore   float64 `json:"trending_score"`
+	PopularityScore float64 `json:"popularity_score"`
+}
+
+// NewAnalyticsHandler creates a new analytics handler
+func NewAnalyticsHandler(
+	installs *analytics.InstallStore,
+	ratings *analytics.RatingStore,
+	indexSyncer *analytics.IndexSyncer,
+	activeUsers *analytics.ActiveUsers,
+	rollup *analytics.MetricsRollup,
+	trending *analytics.TrendingScorer,
+	searchService *search.Service,
+	cache *cache.Cache,
+	cfg *config.Config,
+) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		installs:      installs,
+		ratings:       ratings,
+		indexSyncer:   indexSyncer,
+		activeUsers:   activeUsers,
+		rollup:        rollup,
+		trending:      trending,
+		searchService: searchService,
+		cache:         cache,
+		cfg:           cfg,
+	}
+}
+
+// ServerAnalytics returns a server's metrics, ratings, usage and trending
+// sections. Complete responses are cached; partial ones are not.
+func (h *AnalyticsHandler) ServerAnalytics(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	key := analyticsCachePrefix + serverID
+
+	var cached serverAnalytics
+	if found, err := h.cache.Get(ctx, key, &cached); err != nil {
+		log.Printf("Cache read error: %v", err)
+	} else if found {
+		return c.JSON(cached)
+	}
+
+	// A missing server is an error; an unreachable index only degrades sections
+	if _, err := h.searchService.GetServer(ctx, serverID); err != nil {
+		if errors.Is(err, search.ErrServerNotFound) {
+			return serverLookupError(c, err)
+		}
+		log.Printf("Server lookup error: %v", err)
+	}
+
+	result := h.compose(ctx, serverID)
+
+	complete := true
+	for _, status := range result.Status {
+		complete = complete && status == sectionOK
+	}
+	if complete {
+		ttl := time.Duration(h.cfg.StatsCacheTTL) * time.Second
+		if err := h.cache.Set(ctx, key, result, ttl); err != nil {
+			log.Printf("Cache write error: %v", err)
+		}
+	}
+
+	return c.JSON(result)
+}
+
+// compose loads every section concurrently, recording the status of each
+func (h *AnalyticsHandler) compose(ctx context.Context, serverID string) *serverAnalytics {
+	result := &serverAnalytics{
+		ServerID:    serverID,
+		Status:      make(map[string]string),
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	section := func(name string, load func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			status := sectionOK
+			if err := load(); err != nil {
+				log.Printf("Server analytics %s error for %s: %v", name, serverID, err)
+				status = sectionUnavailable
+			}
+
+			mu.Lock()
+			result.Status[name] = status
+			mu.Unlock()
+		}()
+	}
+
+	section("metrics", func() (err error) {
+		result.Metrics, err = h.metrics(ctx, serverID)
+		return err
+	})
+	section("ratings", func() (err error) {
+		result.Ratings, err = h.ratingSection(ctx, serverID)
+		return err
+	})
+	section("usage", func() (err error) {
+		result.Usage, err = h.usageSection(ctx, serverID)
+		return err
+	})
+	section("trending", func() (err error) {
+		result.Trending, err = h.trendingSection(ctx, serverID)
+		return err
+	})
+
+	wg.Wait()
+
+	return result
+}
+
+// metrics assembles the install, active user and retention metrics
+func (h *AnalyticsHandler) metrics(ctx context.Context, serverID string) (*serverMetrics, error) {
+	counts, err := h.installs.Counts(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := h.activeUsers.Current(ctx, serverID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	retention, err := h.installs.Retention(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	cohorts, err := h.installs.Cohorts(ctx, serverID, cohortWeeks)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons, err := h.installs.UninstallReasons(ctx, serverID, topUninstallReasons)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &serverMetrics{
+		TotalInstalls:      counts.Installs,
+		ActiveInstalls:     counts.ActiveInstalls,
+		DailyActiveUsers:   active.DAU,
+		WeeklyActiveUsers:  active.WAU,
+		MonthlyActiveUsers: active.MAU,
+		RetentionRate:      retention,
+		Cohorts:            cohorts,
+		UninstallReasons:   reasons,
+	}
+	if counts.Installs > 0 {
+		metrics.UninstallRate = float64(counts.Uninstalls) / float64(counts.Installs)
+	}
+
+	return metrics, nil
+}
+
+// ratingSection assembles the rating summary and recent trend
+func (h *AnalyticsHandler) ratingSection(ctx context.Context, serverID string) (*ratingAnalytics, error) {
+	summary, err := h.ratings.Summary(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Weighted, err = h.indexSyncer.WeightedAverage(ctx, summary.Total, summary.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	trend, err := h.ratings.Trend(ctx, serverID, ratingTrendWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ratingAnalytics{RatingSummary: summary, RecentTrend: trend}, nil
+}
+
+// usageSection breaks down the last days of usage by capability type and name
+func (h *AnalyticsHandler) usageSection(ctx context.Context, serverID string) (fiber.Map, error) {
+	today := analytics.BucketStart(model.IntervalDay, time.Now())
+	buckets, err := h.rollup.Buckets(ctx, serverID, model.IntervalDay, today.AddDate(0, 0, -usageWindowDays+1), today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge the daily buckets per capability type
+	byType := map[string]*model.UsageMetrics{
+		model.UsageToolCall:    {},
+		model.UsagePromptUse:   {},
+		model.UsageTemplateUse: {},
+	}
+	byName := map[string]map[string]int64{
+		model.UsageToolCall:    {},
+		model.UsagePromptUse:   {},
+		model.UsageTemplateUse: {},
+	}
+	for i := range buckets {
+		for j := range buckets[i].Capabilities {
+			capability := &buckets[i].Capabilities[j]
+			if totals, ok := byType[capability.Type]; ok {
+				totals.Merge(&capability.UsageMetrics)
+				byName[capability.Type][capability.Name] += capability.Count
+			}
+		}
+	}
+
+	section := func(eventType, nameKey string) fiber.Map {
+		totals := byType[eventType]
+		totals.Finalize()
+		return fiber.Map{
+			"total":          totals.Count,
+			"daily_average":  float64(totals.Count) / usageWindowDays,
+			"success_rate":   totals.SuccessRate,
+			"latency_p95_ms": totals.LatencyP95,
+			nameKey:          byName[eventType],
+		}
+	}
+
+	return fiber.Map{
+		"days":              usageWindowDays,
+		"tool_calls":        section(model.UsageToolCall, "by_tool"),
+		"prompt_executions": section(model.UsagePromptUse, "by_prompt"),
+		"template_uses":     section(model.UsageTemplateUse, "by_template"),
+	}, nil
+}
+
+// trendingSection reports the server's latest trending rank and velocity
+func (h *AnalyticsHandler) trendingSection(ctx context.Context, serverID string) (*trendingSection, error) {
+	data, err := h.trending.Latest(ctx, serverID)
+	if errors.Is(err, analytics.ErrNotFound) {
+		// No recent activity to score
+		return &trendingSection{Velocity: analytics.VelocitySteady}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trending data: %w", err)
+	}
+
+	section := &trendingSection{
+		GrowthRate:      data.GrowthRate,
+		Velocity:        data.Velocity,
+		TrendingScore:   data.TrendingScore,
+		PopularityScore: data.PopularityScore,
+	}
+	if section.Velocity == "" {
+		section.Velocity = analytics.VelocitySteady
+	}
+	if data.Rank > 0 {
+		section.Rank = &data.Rank
+	}
+	if data.PreviousRank > 0 {
+		section.PreviousRank = &data.PreviousRank
+	}
+
+	return section, nil
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RoleAdmin is the token role granted access to admin endpoints
+const RoleAdmin = "admin"
+
+// userContextKey is the fiber.Ctx locals key holding the authenticated user
+const userContextKey = "user"
+
+// UserClaims are the claims carried by plugged.in user tokens
+type UserClaims struct {
+	Role string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the authenticated user's ID, or an empty string for
+// anonymous requests
+func (c *UserClaims) UserID() string {
+	if c == nil {
+		return ""
+	}
+	return c.Subject
+}
+
+// UserAuthMiddleware validates the bearer user token signed with secret
+func UserAuthMiddleware(secret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := parseUserToken(c, secret)
+		if err != nil || claims == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or missing user token",
+			})
+		}
+
+		c.Locals(userContextKey, claims)
+		return c.Next()
+	}
+}
+
+// OptionalUserAuthMiddleware identifies the user when a bearer token is
+// sent and lets anonymous requests through. Invalid tokens are rejected.
+func OptionalUserAuthMiddleware(secret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, err := parseUserToken(c, secret)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid user token",
+			})
+		}
+
+		if claims != nil {
+			c.Locals(userContextKey, claims)
+		}
+		return c.Next()
+	}
+}
+
+// AdminAuthMiddleware allows only users with the admin role. It must run
+// after UserAuthMiddleware.
+func AdminAuthMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := currentUser(c)
+		if user == nil || user.Role != RoleAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Admin access required",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// currentUser returns the authenticated user, or nil for anonymous requests
+func currentUser(c *fiber.Ctx) *UserClaims {
+	claims, _ := c.Locals(userContextKey).(*UserClaims)
+	return claims
+}
+
+// parseUserToken parses the Authorization header. It returns nil claims
+// without error when no token was sent.
+func parseUserToken(c *fiber.Ctx, secret string) (*UserClaims, error) {
+	header := c.Get(fiber.HeaderAuthorization)
+	if header == "" {
+		return nil, nil
+	}
+
+	tokenString, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return nil, jwt.ErrTokenMalformed
+	}
+
+	claims := &UserClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/cache"
+	"github.com/pluggedin/mcp-analytics/internal/config"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// featuredCachePrefix prefixes every cached public featured list
+const featuredCachePrefix = "discovery:featured:"
+
+// DiscoveryHandler serves the trending, top-rated, recent and featured lists
+type DiscoveryHandler struct {
+	searchService *search.Service
+	featuredStore *analytics.FeaturedStore
+	experiments   *analytics.ExperimentStore
+	cache         *cache.Cache
+	cfg           *config.Config
+}
+
+// NewDiscoveryHandler creates a new discovery handler
+func NewDiscoveryHandler(searchService *search.Service, featuredStore *analytics.FeaturedStore, experiments *analytics.ExperimentStore, cache *cache.Cache, cfg *config.Config) *DiscoveryHandler {
+	return &DiscoveryHandler{
+		searchService: searchService,
+		featuredStore: featuredStore,
+		experiments:   experiments,
+		cache:         cache,
+		cfg:           cfg,
+	}
+}
+
+// Trending returns servers ordered by trending score
+func (h *DiscoveryHandler) Trending(c *fiber.Ctx) error {
+	query := h.parseQuery(c, 20)
+	ttl := time.Duration(h.cfg.TrendingCacheTTL) * time.Second
+
+	return h.serveList(c, "trending", model.ExperimentTrending, query, ttl, h.searchService.Trending)
+}
+
+// TopRated returns the best rated servers with enough reviews
+func (h *DiscoveryHandler) TopRated(c *fiber.Ctx) error {
+	query := h.parseQuery(c, 20)
+	query.MinReviews = c.QueryInt("min_reviews", h.cfg.MinRatingCount)
+	if query.MinReviews < 0 {
+		query.MinReviews = 0
+	}
+	ttl := time.Duration(h.cfg.CacheTTL) * time.Second
+
+	return h.serveList(c, "top-rated", model.ExperimentTopRated, query, ttl, h.searchService.TopRated)
+}
+
+// Recent returns newly added or recently updated servers
+func (h *DiscoveryHandler) Recent(c *fiber.Ctx) error {
+	query := h.parseQuery(c, 20)
+	query.ActivityType = c.Query("activity_type", search.ActivityUpdated)
+	if query.ActivityType != search.ActivityNew && query.ActivityType != search.ActivityUpdated {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "activity_type must be 'new' or 'updated'",
+		})
+	}
+	ttl := time.Duration(h.cfg.CacheTTL) * time.Second
+
+	return h.serveList(c, "recent", model.ExperimentRecent, query, ttl, h.searchService.Recent)
+}
+
+// Featured returns the currently active curated placements for a category,
+// or the global placements when no category is given
+func (h *DiscoveryHandler) Featured(c *fiber.Ctx) error {
+	query := h.parseQuery(c, 10)
+	ttl := time.Duration(h.cfg.FeaturedCacheTTL) * time.Second
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	key := fmt.Sprintf("%s%s:%d", featuredCachePrefix, query.Category, query.Limit)
+
+	var featured []model.FeaturedServer
+	err := h.cache.Remember(ctx, key, ttl, &featured, func() (interface{}, error) {
+		return h.activeFeatured(ctx, query)
+	})
+	if err != nil {
+		log.Printf("Discovery featured error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load featured servers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"featured": featured,
+		"total":    len(featured),
+	})
+}
+
+// activeFeatured loads the active placements and hydrates them with live server data.
+// Placements whose server has left the index are dropped, keeping at most query.Limit.
+func (h *DiscoveryHandler) activeFeatured(ctx context.Context, query search.DiscoveryQuery) ([]model.FeaturedServer, error) {
+	// Over-fetch so servers missing from the index do not shorten the list
+	entries, err := h.featuredStore.Active(ctx, query.Category, h.cfg.SearchMaxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ServerID
+	}
+
+	servers, err := h.searchService.GetServers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*model.ServerDetail, len(servers))
+	for i := range servers {
+		byID[servers[i].ID] = &servers[i]
+	}
+
+	featured := make([]model.FeaturedServer, 0, query.Limit)
+	for _, entry := range entries {
+		if server, ok := byID[entry.ServerID]; ok && len(featured) < query.Limit {
+			entry.Server = server
+			featured = append(featured, entry)
+		}
+	}
+
+	return featured, nil
+}
+
+// parseQuery reads the category and limit parameters shared by all lists
+func (h *DiscoveryHandler) parseQuery(c *fiber.Ctx, defaultLimit int) search.DiscoveryQuery {
+	query := search.DiscoveryQuery{
+		Category: c.Query("category"),
+		Limit:    c.QueryInt("limit", defaultLimit),
+	}
+
+	// Validate limit
+	if query.Limit < 1 {
+		query.Limit = defaultLimit
+	}
+	if query.Limit > h.cfg.SearchMaxResults {
+		query.Limit = h.cfg.SearchMaxResults
+	}
+
+	return query
+}
+
+// serveList loads a discovery list through the cache and writes it to the
+// response. A running experiment on the surface may change the list's order;
+// each variant is cached separately.
+func (h *DiscoveryHandler) serveList(
+	c *fiber.Ctx,
+	name string,
+	surface string,
+	query search.DiscoveryQuery,
+	ttl time.Duration,
+	fetch func(context.Context, search.DiscoveryQuery) ([]model.ServerDetail, error),
+) error {
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	key := fmt.Sprintf("discovery:%s:%s:%d:%d:%s", name, query.Category, query.Limit, query.MinReviews, query.ActivityType)
+
+	unitType, unitID := experimentUnit(c)
+	assignment := h.experiments.Assign(surface, unitType, unitID)
+	if assignment != nil {
+		query.Ranking = &assignment.Params
+		key += fmt.Sprintf(":experiment:%d:%s", assignment.ExperimentID, assignment.Variant)
+	}
+
+	var servers []model.ServerDetail
+	err := h.cache.Remember(ctx, key, ttl, &servers, func() (interface{}, error) {
+		return fetch(ctx, query)
+	})
+	if err != nil {
+		log.Printf("Discovery %s error: %v", name, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load servers",
+		})
+	}
+
+	response := fiber.Map{
+		"servers": servers,
+		"total":   len(servers),
+	}
+	if assignment != nil {
+		response["experiment"] = assignment
+	}
+
+	return c.JSON(response)
+}
@@ -5,12 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
 	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/realtime"
 	"github.com/pluggedin/mcp-analytics/internal/search"
 )
 
@@ -34,13 +35,18 @@ type Event struct {
 // EventHandler handles internal event notifications from Registry
 type EventHandler struct {
 	searchService *search.Service
+	indexSyncer   *analytics.IndexSyncer
+	hub           *realtime.Hub
 	eventQueue    chan Event
 }
 
-// NewEventHandler creates a new event handler
-func NewEventHandler(searchService *search.Service) *EventHandler {
+// NewEventHandler creates a new event handler. Processed events are published
+// to hub, which may be nil.
+func NewEventHandler(searchService *search.Service, indexSyncer *analytics.IndexSyncer, hub *realtime.Hub) *EventHandler {
 	h := &EventHandler{
 		searchService: searchService,
+		indexSyncer:   indexSyncer,
+		hub:           hub,
 		eventQueue:    make(chan Event, 1000), // Buffer up to 1000 events
 	}
 
@@ -119,6 +125,11 @@ func (h *EventHandler) handleServerAdded(ctx context.Context, event Event) {
 		return
 	}
 
+	// Restore install, rating and usage stats tracked by this service
+	h.indexSyncer.MarkDirty(serverDetail.ID)
+
+	h.hub.Publish(realtime.ChannelNewServers, realtime.TypeNewServer, serverDetail.ID, serverDetail)
+
 	log.Printf("Successfully indexed server: %s", event.ServerID)
 }
 
@@ -145,6 +156,8 @@ func (h *EventHandler) handleServerUpdated(ctx context.Context, event Event) {
 		return
 	}
 
+	h.hub.Publish("", realtime.TypeServerUpdated, server.ID, server)
+
 	log.Printf("Successfully updated server: %s", event.ServerID)
 }
 
@@ -158,6 +171,8 @@ func (h *EventHandler) handleServerDeleted(ctx context.Context, event Event) {
 		return
 	}
 
+	h.hub.Publish("", realtime.TypeServerDeleted, event.ServerID, fiber.Map{"id": event.ServerID})
+
 	log.Printf("Successfully deleted server: %s", event.ServerID)
 }
 
@@ -179,16 +194,6 @@ func (h *EventHandler) eventDataToServerDetail(data map[string]interface{}) (*mo
 		server.ID = serverID
 	}
 
-	// Map total_installs to install_count if present
-	if totalInstalls, ok := data["total_installs"].(float64); ok {
-		server.InstallCount = int64(totalInstalls)
-	}
-
-	// Map rating to rating_average if present
-	if rating, ok := data["rating"].(float64); ok {
-		server.RatingAverage = rating
-	}
-
 	// Set source field if not present - determine from server name/ID
 	if server.Source == "" {
 		server.Source = determineServerSource(server.ID, server.Name)
@@ -234,6 +239,19 @@ func (h *EventHandler) applyUpdates(server *model.ServerDetail, updates map[stri
 	updatedServer.ID = server.ID
 	updatedServer.IndexedAt = server.IndexedAt
 	updatedServer.LastUpdated = time.Now()
+
+	// Install, rating and usage stats and scores are tracked here, not by the Registry
+	updatedServer.InstallCount = server.InstallCount
+	updatedServer.ActiveInstallCount = server.ActiveInstallCount
+	updatedServer.RatingAverage = server.RatingAverage
+	updatedServer.RatingCount = server.RatingCount
+	updatedServer.RatingWeighted = server.RatingWeighted
+	updatedServer.ToolCallCount = server.ToolCallCount
+	updatedServer.PromptUseCount = server.PromptUseCount
+	updatedServer.TemplateUseCount = server.TemplateUseCount
+	updatedServer.PopularityScore = server.PopularityScore
+	updatedServer.TrendingScore = server.TrendingScore
+	updatedServer.QualityScore = server.QualityScore
 	
 	// Preserve source if not provided in updates
 	if updatedServer.Source == "" {
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// sessionHeader carries the client's session ID, which experiments assign
+// anonymous users by
+const sessionHeader = "X-Session-ID"
+
+// Limits for experiment definitions
+const (
+	maxExperimentVariants = 10
+	maxVariantWeight      = 1000
+)
+
+// experimentKeyPattern restricts keys and variant names to slugs
+var experimentKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ExperimentHandler manages ranking experiments and reports their results
+type ExperimentHandler struct {
+	store *analytics.ExperimentStore
+}
+
+// experimentRequest is the payload for POST /v1/admin/experiments
+type experimentRequest struct {
+	Key         string                    `json:"key"`
+	Description string                    `json:"description"`
+	Surface     string                    `json:"surface"`
+	Variants    []model.ExperimentVariant `json:"variants"`
+}
+
+// NewExperimentHandler creates a new experiment handler
+func NewExperimentHandler(store *analytics.ExperimentStore) *ExperimentHandler {
+	return &ExperimentHandler{store: store}
+}
+
+// Create defines a draft experiment. The first variant is the control.
+func (h *ExperimentHandler) Create(c *fiber.Ctx) error {
+	var req experimentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if err := validateExperiment(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	experiment := &model.Experiment{
+		Key:         req.Key,
+		Description: truncate(strings.TrimSpace(req.Description), 1000),
+		Surface:     req.Surface,
+		Variants:    req.Variants,
+		CreatedBy:   currentUser(c).UserID(),
+	}
+
+	err := h.store.Create(c.Context(), experiment)
+	switch {
+	case errors.Is(err, analytics.ErrExperimentExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Experiment key already exists",
+		})
+	case err != nil:
+		log.Printf("Experiment create error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create experiment",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(experiment)
+}
+
+// List returns experiments, optionally in one state
+func (h *ExperimentHandler) List(c *fiber.Ctx) error {
+	status := c.Query("status")
+	switch status {
+	case "", model.ExperimentDraft, model.ExperimentRunning, model.ExperimentStopped:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be one of draft, running, stopped",
+		})
+	}
+
+	experiments, err := h.store.List(c.Context(), status)
+	if err != nil {
+		log.Printf("Experiment list error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load experiments",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"experiments": experiments,
+	})
+}
+
+// Get returns an experiment
+func (h *ExperimentHandler) Get(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid experiment ID",
+		})
+	}
+
+	experiment, err := h.store.Get(c.Context(), int64(id))
+	if err != nil {
+		return experimentError(c, err)
+	}
+
+	return c.JSON(experiment)
+}
+
+// Start runs a draft experiment
+func (h *ExperimentHandler) Start(c *fiber.Ctx) error {
+	return h.transition(c, h.store.Start)
+}
+
+// Stop ends a running experiment
+func (h *ExperimentHandler) Stop(c *fiber.Ctx) error {
+	return h.transition(c, h.store.Stop)
+}
+
+// Report compares click-through and install conversion between variants
+func (h *ExperimentHandler) Report(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid experiment ID",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := h.store.Report(ctx, int64(id))
+	if err != nil {
+		return experimentError(c, err)
+	}
+
+	return c.JSON(report)
+}
+
+// transition applies a status change to the experiment in the path
+func (h *ExperimentHandler) transition(c *fiber.Ctx, apply func(context.Context, int64) (*model.Experiment, error)) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid experiment ID",
+		})
+	}
+
+	experiment, err := apply(c.Context(), int64(id))
+	if err != nil {
+		return experimentError(c, err)
+	}
+
+	return c.JSON(experiment)
+}
+
+// experimentError maps experiment store errors to responses
+func experimentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, analytics.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Experiment not found",
+		})
+	case errors.Is(err, analytics.ErrExperimentConflict):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Another experiment is already running on this surface",
+		})
+	case errors.Is(err, analytics.ErrExperimentState):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Only draft experiments can be started and running experiments stopped",
+		})
+	default:
+		log.Printf("Experiment error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process experiment",
+		})
+	}
+}
+
+// validateExperiment checks an experiment definition
+func validateExperiment(req *experimentRequest) error {
+	if !experimentKeyPattern.MatchString(req.Key) {
+		return errors.New("key must be a lowercase slug of at most 64 characters")
+	}
+	if !slices.Contains(model.ExperimentSurfaces, req.Surface) {
+		return fmt.Errorf("surface must be one of %s", strings.Join(model.ExperimentSurfaces, ", "))
+	}
+	if len(req.Variants) < 2 || len(req.Variants) > maxExperimentVariants {
+		return fmt.Errorf("an experiment needs between 2 and %d variants", maxExperimentVariants)
+	}
+
+	names := make(map[string]bool, len(req.Variants))
+	for _, variant := range req.Variants {
+		if !experimentKeyPattern.MatchString(variant.Name) {
+			return errors.New("variant names must be lowercase slugs of at most 64 characters")
+		}
+		if names[variant.Name] {
+			return fmt.Errorf("duplicate variant: %s", variant.Name)
+		}
+		names[variant.Name] = true
+
+		if variant.Weight < 1 || variant.Weight > maxVariantWeight {
+			return fmt.Errorf("variant weights must be between 1 and %d", maxVariantWeight)
+		}
+		if err := search.ValidateRanking(variant.Params); err != nil {
+			return fmt.Errorf("variant %s: %w", variant.Name, err)
+		}
+		if req.Surface != model.ExperimentSearch && (len(variant.Params.FieldBoosts) > 0 || len(variant.Params.ScoreBoosts) > 0) {
+			return fmt.Errorf("variant %s: field and score boosts only apply to search", variant.Name)
+		}
+	}
+
+	return nil
+}
+
+// experimentUnit identifies who experiments assign a variant to: the
+// signed-in user, or else the client's session
+func experimentUnit(c *fiber.Ctx) (unitType, unitID string) {
+	if userID := currentUser(c).UserID(); userID != "" {
+		return model.UnitUser, userID
+	}
+	if session := sessionID(c); session != "" {
+		return model.UnitSession, session
+	}
+	return "", ""
+}
+
+// sessionID returns the client's session ID, ignoring oversized values
+func sessionID(c *fiber.Ctx) string {
+	session := strings.TrimSpace(c.Get(sessionHeader))
+	if len(session) > maxInstallFieldLength {
+		return ""
+	}
+	return session
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/cache"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// maxBlurbLength limits the editorial blurb shown with a featured server
+const maxBlurbLength = 500
+
+// FeaturedHandler serves the admin endpoints for curating featured servers
+type FeaturedHandler struct {
+	store         *analytics.FeaturedStore
+	searchService *search.Service
+	cache         *cache.Cache
+
+	// checkedAt is when ArchiveExpired last looked for started placements
+	checkedAt time.Time
+}
+
+// featuredRequest is the admin payload for creating or updating a placement
+type featuredRequest struct {
+	ServerID     string     `json:"server_id"`
+	Category     string     `json:"category"`
+	Blurb        string     `json:"blurb"`
+	DisplayOrder int        `json:"display_order"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+}
+
+// NewFeaturedHandler creates a new featured admin handler
+func NewFeaturedHandler(store *analytics.FeaturedStore, searchService *search.Service, cache *cache.Cache) *FeaturedHandler {
+	return &FeaturedHandler{
+		store:         store,
+		searchService: searchService,
+		cache:         cache,
+		checkedAt:     time.Now(),
+	}
+}
+
+// List returns featured placements, optionally filtered by status
+func (h *FeaturedHandler) List(c *fiber.Ctx) error {
+	featured, err := h.store.List(c.Context(), c.Query("status"))
+	if err != nil {
+		log.Printf("Featured list error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list featured servers",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"featured": featured,
+		"total":    len(featured),
+	})
+}
+
+// Get returns a single featured placement
+func (h *FeaturedHandler) Get(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid featured ID",
+		})
+	}
+
+	featured, err := h.store.Get(c.Context(), int64(id))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	return c.JSON(featured)
+}
+
+// Create features a server globally or in a category
+func (h *FeaturedHandler) Create(c *fiber.Ctx) error {
+	var req featuredRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if req.ServerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "server_id is required",
+		})
+	}
+
+	featured := &model.FeaturedServer{ServerID: req.ServerID}
+	if err := applyFeaturedRequest(featured, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	// Only servers in the index can be featured
+	if _, err := h.searchService.GetServer(ctx, req.ServerID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	if user := currentUser(c); user != nil {
+		featured.CreatedBy = user.UserID()
+	}
+
+	if err := h.store.Create(ctx, featured); err != nil {
+		return h.storeError(c, err)
+	}
+
+	h.invalidate(ctx)
+
+	return c.Status(fiber.StatusCreated).JSON(featured)
+}
+
+// Update changes the category, blurb, ordering or schedule of a placement
+func (h *FeaturedHandler) Update(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid featured ID",
+		})
+	}
+
+	var req featuredRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	featured, err := h.store.Get(c.Context(), int64(id))
+	if err != nil {
+		return h.storeError(c, err)
+	}
+
+	if err := applyFeaturedRequest(featured, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.store.Update(c.Context(), featured); err != nil {
+		return h.storeError(c, err)
+	}
+
+	h.invalidate(c.Context())
+
+	return c.JSON(featured)
+}
+
+// Delete removes a placement
+func (h *FeaturedHandler) Delete(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid featured ID",
+		})
+	}
+
+	if err := h.store.Delete(c.Context(), int64(id)); err != nil {
+		return h.storeError(c, err)
+	}
+
+	h.invalidate(c.Context())
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ArchiveExpired archives placements past their end time, and drops the
+// cached lists when placements are archived or scheduled ones go live. It
+// runs as a scheduled job.
+func (h *FeaturedHandler) ArchiveExpired(ctx context.Context) error {
+	archived, err := h.store.ArchiveExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	started, err := h.store.Started(ctx, h.checkedAt, now)
+	if err != nil {
+		return err
+	}
+	h.checkedAt = now
+
+	if archived > 0 {
+		log.Printf("Archived %d expired featured servers", archived)
+	}
+	if archived > 0 || started > 0 {
+		h.invalidate(ctx)
+	}
+
+	return nil
+}
+
+// invalidate drops the cached public featured lists
+func (h *FeaturedHandler) invalidate(ctx context.Context) {
+	if err := h.cache.DeletePrefix(ctx, featuredCachePrefix); err != nil {
+		log.Printf("Featured cache invalidation warning: %v", err)
+	}
+}
+
+// storeError maps featured store errors to responses
+func (h *FeaturedHandler) storeError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, analytics.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Featured server not found",
+		})
+	}
+
+	log.Printf("Featured store error: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to save featured server",
+	})
+}
+
+// applyFeaturedRequest validates req and copies its editable fields onto featured
+func applyFeaturedRequest(featured *model.FeaturedServer, req featuredRequest) error {
+	if len(req.Blurb) > maxBlurbLength {
+		return errors.New("blurb must be at most 500 characters")
+	}
+
+	startsAt := featured.StartsAt
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	if startsAt.IsZero() {
+		startsAt = time.Now()
+	}
+
+	if req.EndsAt != nil && !req.EndsAt.After(startsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	featured.Category = req.Category
+	featured.Blurb = req.Blurb
+	featured.DisplayOrder = req.DisplayOrder
+	featured.StartsAt = startsAt
+	featured.EndsAt = req.EndsAt
+
+	return nil
+}
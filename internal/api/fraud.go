@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+)
+
+// FraudHandler serves the admin view of install fraud detections
+type FraudHandler struct {
+	detector *analytics.FraudDetector
+}
+
+// NewFraudHandler creates a new fraud handler
+func NewFraudHandler(detector *analytics.FraudDetector) *FraudHandler {
+	return &FraudHandler{detector: detector}
+}
+
+// List returns flagged servers and accounts with the evidence (open flags
+// by default)
+func (h *FraudHandler) List(c *fiber.Ctx) error {
+	status := c.Query("status", analytics.FraudOpen)
+	if status != analytics.FraudOpen && status != analytics.FraudDismissed {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be one of open, dismissed",
+		})
+	}
+
+	subjectType := c.Query("subject_type")
+	switch subjectType {
+	case "", analytics.FraudSubjectServer, analytics.FraudSubjectUser:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "subject_type must be one of server, user",
+		})
+	}
+
+	page, limit := pageParams(c, 50, 200)
+
+	flags, total, err := h.detector.Flags(c.Context(), status, subjectType, (page-1)*limit, limit)
+	if err != nil {
+		log.Printf("Fraud flags error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load fraud flags",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":     status,
+		"flags":      flags,
+		"pagination": newPagination(page, limit, total),
+	})
+}
+
+// Dismiss clears a false positive so its installs count again
+func (h *FraudHandler) Dismiss(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid flag ID",
+		})
+	}
+
+	flag, err := h.detector.Dismiss(c.Context(), int64(id), currentUser(c).UserID())
+	switch {
+	case errors.Is(err, analytics.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Fraud flag not found",
+		})
+	case err != nil:
+		log.Printf("Fraud dismiss error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to dismiss fraud flag",
+		})
+	}
+
+	return c.JSON(flag)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// defaultFunnelRange is the funnel report window when from is omitted
+const defaultFunnelRange = 30 * 24 * time.Hour
+
+// FunnelHandler tracks server views and serves discovery funnel reports
+type FunnelHandler struct {
+	store         *analytics.FunnelStore
+	searchService *search.Service
+}
+
+// viewRequest is the payload for POST /v1/views
+type viewRequest struct {
+	ServerID string `json:"server_id"`
+	Source   string `json:"source"`
+	Referrer string `json:"referrer"`
+	Position *int   `json:"position"`
+	SearchID string `json:"search_id"`
+}
+
+// NewFunnelHandler creates a new funnel handler
+func NewFunnelHandler(store *analytics.FunnelStore, searchService *search.Service) *FunnelHandler {
+	return &FunnelHandler{
+		store:         store,
+		searchService: searchService,
+	}
+}
+
+// TrackView records that a user opened a server's detail page from a
+// discovery surface
+func (h *FunnelHandler) TrackView(c *fiber.Ctx) error {
+	var req viewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if req.ServerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "server_id is required",
+		})
+	}
+	if req.Position != nil && *req.Position < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "position must be at least 1",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, req.ServerID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	view := &model.ServerView{
+		ServerID:  req.ServerID,
+		UserID:    currentUser(c).UserID(),
+		Source:    truncate(strings.TrimSpace(req.Source), maxInstallFieldLength),
+		Referrer:  truncate(strings.TrimSpace(req.Referrer), maxInstallFieldLength),
+		Position:  req.Position,
+		SearchID:  truncate(req.SearchID, maxInstallFieldLength),
+		SessionID: sessionID(c),
+	}
+
+	if err := h.store.RecordView(ctx, view); err != nil {
+		log.Printf("View tracking error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record view",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(view)
+}
+
+// ServerFunnel returns a server's discovery funnel per surface
+func (h *FunnelHandler) ServerFunnel(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	from, to, err := parseFunnelRange(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, serverID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	return h.respond(ctx, c, serverID, from, to)
+}
+
+// PlatformFunnel returns the discovery funnel across all servers
+func (h *FunnelHandler) PlatformFunnel(c *fiber.Ctx) error {
+	from, to, err := parseFunnelRange(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	return h.respond(ctx, c, "", from, to)
+}
+
+// respond loads and writes a funnel report
+func (h *FunnelHandler) respond(ctx context.Context, c *fiber.Ctx, serverID string, from, to time.Time) error {
+	funnel, err := h.store.Funnel(ctx, serverID, from, to)
+	if err != nil {
+		log.Printf("Funnel report error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load funnel",
+		})
+	}
+
+	return c.JSON(funnel)
+}
+
+// parseFunnelRange reads the from and to query parameters. A date-only to
+// includes that whole day.
+func parseFunnelRange(c *fiber.Ctx) (from, to time.Time, err error) {
+	to = time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			return from, to, errors.New("to must be RFC 3339 or YYYY-MM-DD")
+		}
+		if !strings.Contains(value, "T") {
+			to = to.AddDate(0, 0, 1)
+		}
+	}
+
+	from = to.Add(-defaultFunnelRange)
+	if value := c.Query("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			return from, to, errors.New("from must be RFC 3339 or YYYY-MM-DD")
+		}
+	}
+
+	if !from.Before(to) {
+		return from, to, errors.New("from must be before to")
+	}
+
+	return from, to, nil
+}
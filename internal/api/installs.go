@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// Field length limits for install tracking payloads
+const (
+	maxInstallFieldLength = 64
+	maxFeedbackLength     = 2000
+	maxUserAgentLength    = 512
+)
+
+// InstallHandler tracks server installs and uninstalls
+type InstallHandler struct {
+	store         *analytics.InstallStore
+	indexSyncer   *analytics.IndexSyncer
+	activeUsers   *analytics.ActiveUsers
+	searchService *search.Service
+}
+
+// installRequest is the payload for POST /v1/installs
+type installRequest struct {
+	ServerID    string                 `json:"server_id"`
+	Platform    string                 `json:"platform"`
+	AppVersion  string                 `json:"app_version"`
+	PackageType string                 `json:"package_type"`
+	Metadata    map[string]interface{} `json:"metadata"`
+}
+
+// uninstallRequest is the payload for POST /v1/uninstalls
+type uninstallRequest struct {
+	ServerID string `json:"server_id"`
+	Reason   string `json:"reason"`
+	Feedback string `json:"feedback"`
+}
+
+// NewInstallHandler creates a new install handler
+func NewInstallHandler(store *analytics.InstallStore, indexSyncer *analytics.IndexSyncer, activeUsers *analytics.ActiveUsers, searchService *search.Service) *InstallHandler {
+	return &InstallHandler{
+		store:         store,
+		indexSyncer:   indexSyncer,
+		activeUsers:   activeUsers,
+		searchService: searchService,
+	}
+}
+
+// Install records that the authenticated user installed a server
+func (h *InstallHandler) Install(c *fiber.Ctx) error {
+	var req installRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if req.ServerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "server_id is required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.requireServer(ctx, req.ServerID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	install := &model.Install{
+		UserID:      currentUser(c).UserID(),
+		ServerID:    req.ServerID,
+		Platform:    truncate(req.Platform, maxInstallFieldLength),
+		AppVersion:  truncate(req.AppVersion, maxInstallFieldLength),
+		PackageType: truncate(req.PackageType, maxInstallFieldLength),
+		Source:      truncate(metadataString(req.Metadata, "source"), maxInstallFieldLength),
+		Referrer:    truncate(metadataString(req.Metadata, "referrer"), maxInstallFieldLength),
+		Metadata:    req.Metadata,
+		IPAddress:   c.IP(),
+		UserAgent:   truncate(c.Get(fiber.HeaderUserAgent), maxUserAgentLength),
+	}
+
+	created, err := h.store.Install(ctx, install)
+	if err != nil {
+		log.Printf("Install tracking error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record install",
+		})
+	}
+
+	h.activeUsers.TrackQuietly(ctx, install.ServerID, install.UserID, time.Now())
+
+	if !created {
+		return c.JSON(fiber.Map{
+			"status":  "already_installed",
+			"install": install,
+		})
+	}
+
+	h.indexSyncer.MarkDirty(install.ServerID)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status":  "installed",
+		"install": install,
+	})
+}
+
+// Uninstall records that the authenticated user removed a server
+func (h *InstallHandler) Uninstall(c *fiber.Ctx) error {
+	var req uninstallRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if req.ServerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "server_id is required",
+		})
+	}
+
+	reason := strings.ToLower(strings.TrimSpace(req.Reason))
+	if reason == "" {
+		reason = "unspecified"
+	}
+
+	install, err := h.store.Uninstall(
+		c.Context(),
+		currentUser(c).UserID(),
+		req.ServerID,
+		truncate(reason, maxInstallFieldLength),
+		truncate(strings.TrimSpace(req.Feedback), maxFeedbackLength),
+	)
+	if err != nil {
+		if errors.Is(err, analytics.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "No active install for this server",
+			})
+		}
+		log.Printf("Uninstall tracking error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record uninstall",
+		})
+	}
+
+	h.indexSyncer.MarkDirty(install.ServerID)
+
+	return c.JSON(fiber.Map{
+		"status":  "uninstalled",
+		"install": install,
+	})
+}
+
+// requireServer checks that a server exists in the index
+func (h *InstallHandler) requireServer(ctx context.Context, serverID string) error {
+	_, err := h.searchService.GetServer(ctx, serverID)
+	return err
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// Default and maximum time ranges for metrics queries
+const (
+	defaultHourRange  = 48 * time.Hour
+	defaultDayRange   = 30 * 24 * time.Hour
+	maxMetricBuckets  = 744
+	maxActiveUserDays = 365
+)
+
+// MetricsHandler serves rolled-up time-series metrics
+type MetricsHandler struct {
+	rollup        *analytics.MetricsRollup
+	activeUsers   *analytics.ActiveUsers
+	searchService *search.Service
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(rollup *analytics.MetricsRollup, activeUsers *analytics.ActiveUsers, searchService *search.Service) *MetricsHandler {
+	return &MetricsHandler{
+		rollup:        rollup,
+		activeUsers:   activeUsers,
+		searchService: searchService,
+	}
+}
+
+// ServerMetrics returns a server's hourly or daily metric buckets. Buckets
+// without activity are included with zero counts.
+func (h *MetricsHandler) ServerMetrics(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	interval := c.Query("interval", model.IntervalDay)
+	if interval != model.IntervalDay && interval != model.IntervalHour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "interval must be 'day' or 'hour'",
+		})
+	}
+
+	from, to, err := parseMetricsRange(c, interval)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, serverID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	stored, err := h.rollup.Buckets(ctx, serverID, interval, from, to)
+	if err != nil {
+		log.Printf("Metrics error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load metrics",
+		})
+	}
+
+	buckets := fillMetricBuckets(serverID, interval, from, to, stored)
+
+	// Totals over the whole range
+	var totals model.UsageMetrics
+	var installs, uninstalls int64
+	for i := range stored {
+		totals.Merge(&stored[i].Usage)
+		installs += stored[i].Installs
+		uninstalls += stored[i].Uninstalls
+	}
+	totals.Finalize()
+
+	return c.JSON(fiber.Map{
+		"server_id": serverID,
+		"interval":  interval,
+		"from":      from,
+		"to":        to,
+		"buckets":   buckets,
+		"totals": fiber.Map{
+			"usage":      totals,
+			"installs":   installs,
+			"uninstalls": uninstalls,
+		},
+	})
+}
+
+// ActiveUsers returns a server's live DAU/WAU/MAU and its daily history
+func (h *MetricsHandler) ActiveUsers(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > maxActiveUserDays {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("days must be between 1 and %d", maxActiveUserDays),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, serverID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	now := time.Now()
+	current, err := h.activeUsers.Current(ctx, serverID, now)
+	if err != nil {
+		log.Printf("Active users error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load active users",
+		})
+	}
+
+	today := analytics.BucketStart(model.IntervalDay, now)
+	history, err := h.activeUsers.History(ctx, serverID, today.AddDate(0, 0, -days), today)
+	if err != nil {
+		log.Printf("Active users history error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load active users",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"server_id": serverID,
+		"current":   current,
+		"history":   history,
+	})
+}
+
+// parseMetricsRange reads the from/to parameters, aligned to bucket boundaries
+func parseMetricsRange(c *fiber.Ctx, interval string) (from, to time.Time, err error) {
+	step, defaultRange := time.Hour, defaultHourRange
+	if interval == model.IntervalDay {
+		step, defaultRange = 24*time.Hour, defaultDayRange
+	}
+
+	to = analytics.BucketStart(interval, time.Now()).Add(step)
+	if value := c.Query("to"); value != "" {
+		if to, err = parseTimeParam(value); err != nil {
+			return from, to, errors.New("to must be RFC 3339 or YYYY-MM-DD")
+		}
+		// Round up so the bucket containing to is included
+		if start := analytics.BucketStart(interval, to); !start.Equal(to) {
+			to = start.Add(step)
+		}
+	}
+
+	from = to.Add(-defaultRange)
+	if value := c.Query("from"); value != "" {
+		if from, err = parseTimeParam(value); err != nil {
+			return from, to, errors.New("from must be RFC 3339 or YYYY-MM-DD")
+		}
+	}
+	from = analytics.BucketStart(interval, from)
+
+	if !from.Before(to) {
+		return from, to, errors.New("from must be before to")
+	}
+	if to.Sub(from)/step > maxMetricBuckets {
+		return from, to, errors.New("time range is too large for the interval")
+	}
+
+	return from, to, nil
+}
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// fillMetricBuckets returns one bucket per step in [from, to), using stored
+// buckets where present and empty ones elsewhere
+func fillMetricBuckets(serverID, interval string, from, to time.Time, stored []model.MetricBucket) []model.MetricBucket {
+	byStart := make(map[int64]model.MetricBucket, len(stored))
+	for _, bucket := range stored {
+		byStart[bucket.BucketStart.Unix()] = bucket
+	}
+
+	step := time.Hour
+	if interval == model.IntervalDay {
+		step = 24 * time.Hour
+	}
+
+	buckets := []model.MetricBucket{}
+	for start := from; start.Before(to); start = start.Add(step) {
+		bucket, ok := byStart[start.Unix()]
+		if !ok {
+			bucket = model.MetricBucket{
+				ServerID:     serverID,
+				Interval:     interval,
+				BucketStart:  start,
+				Capabilities: []model.CapabilityMetrics{},
+			}
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets
+}
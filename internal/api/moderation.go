@@ -0,0 +1,172 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+)
+
+// maxFlagReasonLength limits the reason users give when flagging a review
+const maxFlagReasonLength = 500
+
+// ModerationHandler serves review flagging and the admin moderation queue
+type ModerationHandler struct {
+	moderator *analytics.ReviewModerator
+}
+
+// moderationRequest is the optional payload for admin moderation actions
+type moderationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// NewModerationHandler creates a new moderation handler
+func NewModerationHandler(moderator *analytics.ReviewModerator) *ModerationHandler {
+	return &ModerationHandler{moderator: moderator}
+}
+
+// Flag reports a review on behalf of the authenticated user
+func (h *ModerationHandler) Flag(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid review ID",
+		})
+	}
+
+	var req moderationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request format",
+			})
+		}
+	}
+
+	reason := truncate(strings.TrimSpace(req.Reason), maxFlagReasonLength)
+	err = h.moderator.Flag(c.Context(), int64(id), currentUser(c).UserID(), reason)
+	switch {
+	case errors.Is(err, analytics.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Review not found",
+		})
+	case errors.Is(err, analytics.ErrOwnReview):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You cannot flag your own review",
+		})
+	case err != nil:
+		log.Printf("Review flag error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to flag review",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "flagged",
+	})
+}
+
+// Queue lists reviews in a moderation state (flagged by default)
+func (h *ModerationHandler) Queue(c *fiber.Ctx) error {
+	status := c.Query("status", analytics.ReviewFlagged)
+	switch status {
+	case analytics.ReviewPending, analytics.ReviewApproved, analytics.ReviewRejected, analytics.ReviewFlagged:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be one of pending, approved, rejected, flagged",
+		})
+	}
+
+	page, limit := pageParams(c, 50, 200)
+
+	reviews, total, err := h.moderator.Queue(c.Context(), status, (page-1)*limit, limit)
+	if err != nil {
+		log.Printf("Moderation queue error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load moderation queue",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":     status,
+		"reviews":    reviews,
+		"pagination": newPagination(page, limit, total),
+	})
+}
+
+// Flags lists the user reports filed against a review
+func (h *ModerationHandler) Flags(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid review ID",
+		})
+	}
+
+	flags, err := h.moderator.Flags(c.Context(), int64(id))
+	if err != nil {
+		log.Printf("Review flags error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load review flags",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"flags": flags,
+	})
+}
+
+// Approve publishes a review
+func (h *ModerationHandler) Approve(c *fiber.Ctx) error {
+	return h.moderate(c, analytics.ReviewApproved)
+}
+
+// Reject hides a review permanently
+func (h *ModerationHandler) Reject(c *fiber.Ctx) error {
+	return h.moderate(c, analytics.ReviewRejected)
+}
+
+// moderate applies an admin moderation decision
+func (h *ModerationHandler) moderate(c *fiber.Ctx, status string) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid review ID",
+		})
+	}
+
+	var req moderationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request format",
+			})
+		}
+	}
+
+	var reasons []string
+	if reason := truncate(strings.TrimSpace(req.Reason), maxFlagReasonLength); reason != "" {
+		reasons = []string{reason}
+	}
+
+	review, err := h.moderator.SetStatus(c.Context(), int64(id), status, currentUser(c).UserID(), reasons)
+	switch {
+	case errors.Is(err, analytics.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Review not found",
+		})
+	case errors.Is(err, analytics.ErrInvalidTransition):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		log.Printf("Review moderation error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to moderate review",
+		})
+	}
+
+	return c.JSON(review)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// QualityHandler serves explainable quality scores
+type QualityHandler struct {
+	scorer *analytics.QualityScorer
+}
+
+// NewQualityHandler creates a new quality handler
+func NewQualityHandler(scorer *analytics.QualityScorer) *QualityHandler {
+	return &QualityHandler{
+		scorer: scorer,
+	}
+}
+
+// ServerQuality returns a server's quality score, its sub-scores and the
+// improvements its publisher could make
+func (h *QualityHandler) ServerQuality(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	report, err := h.scorer.Report(ctx, serverID)
+	if err != nil {
+		if errors.Is(err, search.ErrServerNotFound) {
+			return serverLookupError(c, err)
+		}
+		log.Printf("Quality report error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load quality report",
+		})
+	}
+
+	return c.JSON(report)
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/cache"
+	"github.com/pluggedin/mcp-analytics/internal/config"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// Limits for rank history queries
+const (
+	maxRankHistoryDays = 365
+	maxMoverDays       = 90
+	maxMovers          = 50
+)
+
+// RankHandler serves trending and popularity rank history
+type RankHandler struct {
+	trending      *analytics.TrendingScorer
+	searchService *search.Service
+	cache         *cache.Cache
+	cfg           *config.Config
+}
+
+// NewRankHandler creates a new rank handler
+func NewRankHandler(trending *analytics.TrendingScorer, searchService *search.Service, cache *cache.Cache, cfg *config.Config) *RankHandler {
+	return &RankHandler{
+		trending:      trending,
+		searchService: searchService,
+		cache:         cache,
+		cfg:           cfg,
+	}
+}
+
+// RankHistory returns a server's daily global and category ranks
+func (h *RankHandler) RankHistory(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	days := c.QueryInt("days", 30)
+	if days < 1 || days > maxRankHistoryDays {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("days must be between 1 and %d", maxRankHistoryDays),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, serverID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	since := analytics.BucketStart(model.IntervalDay, time.Now()).AddDate(0, 0, 1-days)
+	history, err := h.trending.RankHistory(ctx, serverID, since)
+	if err != nil {
+		log.Printf("Rank history error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load rank history",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"server_id": serverID,
+		"history":   history,
+	})
+}
+
+// Movers lists the biggest climbers and fallers in the trending or
+// popularity ranking, globally or within a category
+func (h *RankHandler) Movers(c *fiber.Ctx) error {
+	ranking := c.Query("ranking", model.RankingTrending)
+	if ranking != model.RankingTrending && ranking != model.RankingPopularity {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ranking must be 'trending' or 'popularity'",
+		})
+	}
+
+	days := c.QueryInt("days", 7)
+	if days < 1 || days > maxMoverDays {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("days must be between 1 and %d", maxMoverDays),
+		})
+	}
+
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > maxMovers {
+		limit = 10
+	}
+	category := c.Query("category")
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	key := fmt.Sprintf("discovery:movers:%s:%s:%d:%d", ranking, category, days, limit)
+	ttl := time.Duration(h.cfg.TrendingCacheTTL) * time.Second
+
+	var movers analytics.Movers
+	err := h.cache.Remember(ctx, key, ttl, &movers, func() (interface{}, error) {
+		return h.movers(ctx, ranking, category, days, limit)
+	})
+	if err != nil {
+		log.Printf("Rank movers error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load rank movers",
+		})
+	}
+
+	return c.JSON(movers)
+}
+
+// movers loads the rank movers and hydrates them with live server data.
+// Servers that have left the index are dropped, keeping at most limit of each.
+func (h *RankHandler) movers(ctx context.Context, ranking, category string, days, limit int) (*analytics.Movers, error) {
+	// Over-fetch so servers missing from the index do not shorten the lists
+	movers, err := h.trending.Movers(ctx, ranking, category, days, maxMovers)
+	if errors.Is(err, analytics.ErrNotFound) {
+		// Nothing has been ranked yet
+		return &analytics.Movers{
+			Ranking:    ranking,
+			Category:   category,
+			Climbers:   []model.RankMover{},
+			Fallers:    []model.RankMover{},
+			NewEntries: []model.RankMover{},
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, list := range [][]model.RankMover{movers.Climbers, movers.Fallers, movers.NewEntries} {
+		for _, mover := range list {
+			ids = append(ids, mover.ServerID)
+		}
+	}
+
+	servers, err := h.searchService.GetServers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*model.ServerDetail, len(servers))
+	for i := range servers {
+		byID[servers[i].ID] = &servers[i]
+	}
+
+	hydrate := func(list []model.RankMover) []model.RankMover {
+		hydrated := make([]model.RankMover, 0, limit)
+		for _, mover := range list {
+			if server, ok := byID[mover.ServerID]; ok && len(hydrated) < limit {
+				mover.Server = server
+				hydrated = append(hydrated, mover)
+			}
+		}
+		return hydrated
+	}
+	movers.Climbers = hydrate(movers.Climbers)
+	movers.Fallers = hydrate(movers.Fallers)
+	movers.NewEntries = hydrate(movers.NewEntries)
+
+	return movers, nil
+}
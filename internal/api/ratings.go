@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// Review field limits
+const (
+	maxReviewTitleLength   = 200
+	maxReviewContentLength = 5000
+	maxReviewListItems     = 10
+	maxReviewItemLength    = 100
+)
+
+// RatingHandler serves rating submission and review listing
+type RatingHandler struct {
+	store         *analytics.RatingStore
+	indexSyncer   *analytics.IndexSyncer
+	searchService *search.Service
+}
+
+// ratingRequest is the payload for POST /v1/ratings
+type ratingRequest struct {
+	ServerID string `json:"server_id"`
+	Rating   int    `json:"rating"`
+	Review   *struct {
+		Title          string   `json:"title"`
+		Content        string   `json:"content"`
+		Pros           []string `json:"pros"`
+		Cons           []string `json:"cons"`
+		WouldRecommend *bool    `json:"would_recommend"`
+	} `json:"review"`
+}
+
+// voteRequest is the payload for POST /v1/reviews/{id}/vote
+type voteRequest struct {
+	Helpful *bool `json:"helpful"`
+}
+
+// NewRatingHandler creates a new rating handler
+func NewRatingHandler(store *analytics.RatingStore, indexSyncer *analytics.IndexSyncer, searchService *search.Service) *RatingHandler {
+	return &RatingHandler{
+		store:         store,
+		indexSyncer:   indexSyncer,
+		searchService: searchService,
+	}
+}
+
+// Submit creates or edits the authenticated user's rating of a server
+func (h *RatingHandler) Submit(c *fiber.Ctx) error {
+	var req ratingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if req.ServerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "server_id is required",
+		})
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "rating must be between 1 and 5",
+		})
+	}
+
+	review := &model.Review{
+		UserID:   currentUser(c).UserID(),
+		ServerID: req.ServerID,
+		Rating:   req.Rating,
+	}
+
+	if req.Review != nil {
+		review.Title = truncate(strings.TrimSpace(req.Review.Title), maxReviewTitleLength)
+		review.Content = truncate(strings.TrimSpace(req.Review.Content), maxReviewContentLength)
+		review.Pros = cleanReviewItems(req.Review.Pros)
+		review.Cons = cleanReviewItems(req.Review.Cons)
+		review.WouldRecommend = req.Review.WouldRecommend
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, req.ServerID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	created, err := h.store.Upsert(ctx, review)
+	if err != nil {
+		log.Printf("Rating submission error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save rating",
+		})
+	}
+
+	h.indexSyncer.MarkDirty(review.ServerID)
+
+	status := fiber.StatusOK
+	if created {
+		status = fiber.StatusCreated
+	}
+
+	return c.Status(status).JSON(review)
+}
+
+// Reviews returns a page of a server's reviews with its rating distribution
+func (h *RatingHandler) Reviews(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	sort := c.Query("sort", analytics.ReviewSortHelpful)
+	if sort != analytics.ReviewSortHelpful && sort != analytics.ReviewSortRecent && sort != analytics.ReviewSortRating {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "sort must be one of helpful, recent, rating",
+		})
+	}
+
+	page, limit := pageParams(c, 20, 100)
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	reviews, total, err := h.store.List(ctx, serverID, analytics.ReviewQuery{
+		Sort:         sort,
+		VerifiedOnly: c.QueryBool("verified_only", false),
+		Offset:       (page - 1) * limit,
+		Limit:        limit,
+	})
+	if err != nil {
+		log.Printf("Review listing error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load reviews",
+		})
+	}
+
+	summary, err := h.store.Summary(ctx, serverID)
+	if err == nil {
+		summary.Weighted, err = h.indexSyncer.WeightedAverage(ctx, summary.Total, summary.Count)
+	}
+	if err != nil {
+		log.Printf("Rating summary error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load reviews",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"server_id":  serverID,
+		"reviews":    reviews,
+		"summary":    summary,
+		"pagination": newPagination(page, limit, total),
+	})
+}
+
+// Vote records whether the authenticated user found a review helpful
+func (h *RatingHandler) Vote(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid review ID",
+		})
+	}
+
+	var req voteRequest
+	if err := c.BodyParser(&req); err != nil || req.Helpful == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "helpful is required",
+		})
+	}
+
+	err = h.store.Vote(c.Context(), int64(id), currentUser(c).UserID(), *req.Helpful)
+	switch {
+	case errors.Is(err, analytics.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Review not found",
+		})
+	case errors.Is(err, analytics.ErrOwnReview):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You cannot vote on your own review",
+		})
+	case err != nil:
+		log.Printf("Review vote error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record vote",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "recorded",
+	})
+}
+
+// cleanReviewItems trims, drops empty entries and caps a pros/cons list
+func cleanReviewItems(items []string) []string {
+	cleaned := make([]string, 0, len(items))
+	for _, item := range items {
+		item = truncate(strings.TrimSpace(item), maxReviewItemLength)
+		if item == "" {
+			continue
+		}
+		cleaned = append(cleaned, item)
+		if len(cleaned) == maxReviewListItems {
+			break
+		}
+	}
+	return cleaned
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/pluggedin/mcp-analytics/internal/realtime"
+)
+
+// Timeouts and limits for realtime connections
+const (
+	realtimePingInterval = 30 * time.Second
+	realtimePongWait     = 60 * time.Second
+	realtimeWriteWait    = 10 * time.Second
+	realtimeMaxMessage   = 4096
+
+	streamKeepalive = 15 * time.Second
+	streamRetry     = 5 * time.Second
+)
+
+// Actions clients send over a realtime connection
+const (
+	realtimeSubscribe   = "subscribe"
+	realtimeUnsubscribe = "unsubscribe"
+	realtimePing        = "ping"
+)
+
+// RealtimeHandler serves realtime updates over WebSocket and Server-Sent Events
+type RealtimeHandler struct {
+	hub *realtime.Hub
+}
+
+// realtimeRequest is a message a client sends over /v1/realtime
+type realtimeRequest struct {
+	Action   string   `json:"action"`
+	Channels []string `json:"channels"`
+	Servers  []string `json:"servers"`
+}
+
+// NewRealtimeHandler creates a new realtime handler
+func NewRealtimeHandler(hub *realtime.Hub) *RealtimeHandler {
+	return &RealtimeHandler{hub: hub}
+}
+
+// Upgrade rejects requests that are not WebSocket upgrades
+func (h *RealtimeHandler) Upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+			"error": "WebSocket upgrade required",
+		})
+	}
+	return c.Next()
+}
+
+// Connect serves a realtime connection. Clients subscribe to channels and
+// servers with {"action":"subscribe","channels":[...],"servers":[...]} and
+// receive {"type":...,"data":...} messages. Clients that stop answering
+// pings or fall behind are disconnected.
+func (h *RealtimeHandler) Connect() fiber.Handler {
+	// Updates are public, so any origin may connect
+	return websocket.New(h.serve)
+}
+
+// serve runs a connection until either side closes it. Reads stay on this
+// goroutine, since the connection is released once it returns.
+func (h *RealtimeHandler) serve(conn *websocket.Conn) {
+	sub, err := h.hub.Subscribe()
+	if err != nil {
+		code := websocket.CloseTryAgainLater
+		if errors.Is(err, realtime.ErrHubClosed) {
+			code = websocket.CloseGoingAway
+		}
+		writeClose(conn, code, err.Error())
+		return
+	}
+	defer sub.Close()
+
+	replies := make(chan []byte, 1)
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		h.write(conn, sub, replies)
+	}()
+
+	conn.SetReadLimit(realtimeMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(realtimePongWait))
+	})
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		select {
+		case replies <- h.handleMessage(sub, message):
+		case <-sub.Done():
+		}
+	}
+
+	sub.Close()
+	<-written
+}
+
+// write sends events, replies and pings until the subscription ends, then
+// closes the connection
+func (h *RealtimeHandler) write(conn *websocket.Conn, sub *realtime.Subscriber, replies <-chan []byte) {
+	defer conn.Close()
+	defer sub.Close()
+
+	ticker := time.NewTicker(realtimePingInterval)
+	defer ticker.Stop()
+
+	for {
+		var message []byte
+		select {
+		case event := <-sub.Events():
+			message = event.JSON()
+		case message = <-replies:
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(realtimeWriteWait)); err != nil {
+				return
+			}
+			continue
+		case <-sub.Done():
+			switch {
+			case errors.Is(sub.Err(), realtime.ErrSlowSubscriber):
+				writeClose(conn, websocket.ClosePolicyViolation, "Client too slow")
+			case errors.Is(sub.Err(), realtime.ErrHubClosed):
+				writeClose(conn, websocket.CloseGoingAway, "Server shutting down")
+			}
+			return
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(realtimeWriteWait))
+		if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			return
+		}
+	}
+}
+
+// Stream serves realtime updates as Server-Sent Events. The channels and
+// servers query parameters select comma-separated channels and server IDs;
+// with neither, every channel is streamed. Each event is named after its
+// channel, or its type for server events, and identified by its origin.
+// Clients reconnecting to any instance with Last-Event-ID receive the
+// buffered events they missed, preceded by a resync event when some are no
+// longer buffered.
+func (h *RealtimeHandler) Stream(c *fiber.Ctx) error {
+	channels := queryList(c.Query("channels"))
+	servers := queryList(c.Query("servers"))
+	if len(channels) == 0 && len(servers) == 0 {
+		channels = realtime.Channels
+	}
+
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	if len(lastEventID) > realtime.MaxOriginLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid Last-Event-ID",
+		})
+	}
+
+	sub, err := h.hub.Subscribe()
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Realtime updates unavailable",
+		})
+	}
+	if err := sub.Subscribe(channels, servers); err != nil {
+		sub.Close()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+		// Events delivered while replaying are also queued; skip any already sent
+		var sent uint64
+		if lastEventID != "" {
+			events, complete := h.hub.Replay(sub, lastEventID)
+			if !complete {
+				w.WriteString("event: resync\ndata: {}\n\n")
+			}
+			for _, event := range events {
+				writeStreamEvent(w, event)
+				sent = event.ID
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(streamKeepalive)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event := <-sub.Events():
+				if event.ID <= sent {
+					continue
+				}
+				writeStreamEvent(w, event)
+				sent = event.ID
+			case <-ticker.C:
+				w.WriteString(": keepalive\n\n")
+			case <-sub.Done():
+				return
+			}
+
+			// Flushing fails once the client has gone
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// writeStreamEvent writes an event in Server-Sent Events format
+func writeStreamEvent(w *bufio.Writer, event *realtime.Event) {
+	name := event.Channel
+	if name == "" {
+		name = event.Type
+	}
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Origin, name, event.JSON())
+}
+
+// queryList splits a comma-separated query parameter, dropping empty items
+func queryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// handleMessage applies a client message and returns the reply
+func (h *RealtimeHandler) handleMessage(sub *realtime.Subscriber, message []byte) []byte {
+	var req realtimeRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		return realtimeReply("error", fiber.Map{"error": "Invalid message format"})
+	}
+
+	switch req.Action {
+	case realtimeSubscribe:
+		if err := sub.Subscribe(req.Channels, req.Servers); err != nil {
+			return realtimeReply("error", fiber.Map{"error": err.Error()})
+		}
+		return subscriptionsReply("subscribed", sub)
+	case realtimeUnsubscribe:
+		sub.Unsubscribe(req.Channels, req.Servers)
+		return subscriptionsReply("unsubscribed", sub)
+	case realtimePing:
+		return realtimeReply("pong", nil)
+	default:
+		return realtimeReply("error", fiber.Map{"error": "action must be one of subscribe, unsubscribe, ping"})
+	}
+}
+
+// subscriptionsReply reports the subscriber's current subscriptions
+func subscriptionsReply(replyType string, sub *realtime.Subscriber) []byte {
+	channels, servers := sub.Subscriptions()
+	return realtimeReply(replyType, fiber.Map{
+		"channels": channels,
+		"servers":  servers,
+	})
+}
+
+// realtimeReply encodes a message in the same shape as events
+func realtimeReply(replyType string, data interface{}) []byte {
+	message, _ := json.Marshal(fiber.Map{
+		"type": replyType,
+		"data": data,
+	})
+	return message
+}
+
+// writeClose sends a close message, ignoring errors since the connection is
+// going away regardless
+func writeClose(conn *websocket.Conn, code int, text string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(realtimeWriteWait))
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/cache"
+	"github.com/pluggedin/mcp-analytics/internal/config"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// maxRecommendations bounds the servers returned by the recommendation endpoints
+const maxRecommendations = 50
+
+// RecommendationHandler serves co-install based recommendations
+type RecommendationHandler struct {
+	coInstalls    *analytics.CoInstallModel
+	installs      *analytics.InstallStore
+	searchService *search.Service
+	cache         *cache.Cache
+	cfg           *config.Config
+}
+
+// NewRecommendationHandler creates a new recommendation handler
+func NewRecommendationHandler(coInstalls *analytics.CoInstallModel, installs *analytics.InstallStore, searchService *search.Service, cache *cache.Cache, cfg *config.Config) *RecommendationHandler {
+	return &RecommendationHandler{
+		coInstalls:    coInstalls,
+		installs:      installs,
+		searchService: searchService,
+		cache:         cache,
+		cfg:           cfg,
+	}
+}
+
+// AlsoInstalled returns the servers most often installed by users of a server
+func (h *RecommendationHandler) AlsoInstalled(c *fiber.Ctx) error {
+	serverID, err := serverIDParam(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > maxRecommendations {
+		limit = 10
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := h.searchService.GetServer(ctx, serverID); err != nil {
+		return serverLookupError(c, err)
+	}
+
+	key := fmt.Sprintf("discovery:also-installed:%s:%d", serverID, limit)
+	ttl := time.Duration(h.cfg.CacheTTL) * time.Second
+
+	var coInstalls model.CoInstalls
+	err = h.cache.Remember(ctx, key, ttl, &coInstalls, func() (interface{}, error) {
+		coInstalls, err := h.coInstalls.AlsoInstalled(ctx, serverID)
+		if err != nil {
+			return nil, err
+		}
+		coInstalls.Related, err = h.hydrateRelated(ctx, coInstalls.Related, limit)
+		return coInstalls, err
+	})
+	if err != nil {
+		log.Printf("Also installed error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load related servers",
+		})
+	}
+
+	return c.JSON(coInstalls)
+}
+
+// Recommendations suggests servers for the authenticated user from the
+// servers they currently have installed
+func (h *RecommendationHandler) Recommendations(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > maxRecommendations {
+		limit = 10
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	installed, err := h.installs.ActiveServerIDs(ctx, currentUser(c).UserID())
+	if err != nil {
+		log.Printf("Recommendation installs error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load recommendations",
+		})
+	}
+
+	// Over-fetch so servers missing from the index do not shorten the list
+	recommendations, err := h.coInstalls.Recommend(ctx, installed, maxRecommendations)
+	if err == nil {
+		recommendations, err = h.hydrateRecommendations(ctx, recommendations, limit)
+	}
+	if err != nil {
+		log.Printf("Recommendation error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load recommendations",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"recommendations": recommendations,
+		"based_on":        len(installed),
+	})
+}
+
+// hydrateRelated attaches server details to related servers, dropping those
+// no longer indexed, and keeps at most limit
+func (h *RecommendationHandler) hydrateRelated(ctx context.Context, related []model.RelatedServer, limit int) ([]model.RelatedServer, error) {
+	ids := make([]string, len(related))
+	for i, r := range related {
+		ids[i] = r.ServerID
+	}
+
+	servers, err := h.serversByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	hydrated := make([]model.RelatedServer, 0, limit)
+	for _, r := range related {
+		if server, ok := servers[r.ServerID]; ok && len(hydrated) < limit {
+			r.Server = server
+			hydrated = append(hydrated, r)
+		}
+	}
+
+	return hydrated, nil
+}
+
+// hydrateRecommendations attaches server details to recommendations,
+// dropping those no longer indexed, and keeps at most limit
+func (h *RecommendationHandler) hydrateRecommendations(ctx context.Context, recommendations []model.Recommendation, limit int) ([]model.Recommendation, error) {
+	ids := make([]string, len(recommendations))
+	for i, r := range recommendations {
+		ids[i] = r.ServerID
+	}
+
+	servers, err := h.serversByID(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	hydrated := make([]model.Recommendation, 0, limit)
+	for _, r := range recommendations {
+		if server, ok := servers[r.ServerID]; ok && len(hydrated) < limit {
+			r.Server = server
+			hydrated = append(hydrated, r)
+		}
+	}
+
+	return hydrated, nil
+}
+
+// serversByID loads the indexed servers among ids
+func (h *RecommendationHandler) serversByID(ctx context.Context, ids []string) (map[string]*model.ServerDetail, error) {
+	servers, err := h.searchService.GetServers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*model.ServerDetail, len(servers))
+	for i := range servers {
+		byID[servers[i].ID] = &servers[i]
+	}
+
+	return byID, nil
+}
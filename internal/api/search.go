@@ -0,0 +1,333 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/config"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// Limits for search analytics reports
+const (
+	maxSearchReportDays  = 90
+	maxSearchReportLimit = 200
+	maxReportedPosition  = 50
+)
+
+// SearchHandler serves search and records search analytics
+type SearchHandler struct {
+	searchService   *search.Service
+	searchAnalytics *analytics.SearchAnalytics
+	personalizer    *analytics.Personalizer
+	experiments     *analytics.ExperimentStore
+	cfg             *config.Config
+}
+
+// searchEventRequest is the payload for POST /v1/search/events
+type searchEventRequest struct {
+	SearchID string `json:"search_id"`
+	ServerID string `json:"server_id"`
+	Type     string `json:"type"`
+	Position int    `json:"position"`
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(searchService *search.Service, searchAnalytics *analytics.SearchAnalytics, personalizer *analytics.Personalizer, experiments *analytics.ExperimentStore, cfg *config.Config) *SearchHandler {
+	return &SearchHandler{
+		searchService:   searchService,
+		searchAnalytics: searchAnalytics,
+		personalizer:    personalizer,
+		experiments:     experiments,
+		cfg:             cfg,
+	}
+}
+
+// Search runs a server search and logs it. The response carries a search ID
+// that clients send back with clicks and installs on its results.
+//
+// For signed-in users the top relevance-ordered results are re-ranked by
+// their installs' categories and co-installs, and hide_installed=true leaves out servers
+// they already have installed.
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	query := search.SearchQuery{
+		Query:   c.Query("q"),
+		Sort:    c.Query("sort", "relevance"),
+		Offset:  c.QueryInt("offset", 0),
+		Limit:   c.QueryInt("limit", 20),
+		Filters: make(map[string]interface{}),
+	}
+
+	// Add filters
+	if pkgType := c.Query("package_type"); pkgType != "" {
+		query.Filters["package_type"] = pkgType
+	}
+	if transport := c.Query("transport"); transport != "" {
+		query.Filters["transport"] = transport
+	}
+	if category := c.Query("category"); category != "" {
+		query.Filters["categories"] = category
+	}
+	if source := c.Query("source"); source != "" {
+		query.Filters["source"] = source
+	}
+
+	// Validate offset and limit
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	if query.Limit < 1 {
+		query.Limit = 1
+	}
+	if query.Limit > h.cfg.SearchMaxResults {
+		query.Limit = h.cfg.SearchMaxResults
+	}
+
+	// Ranking experiments
+	unitType, unitID := experimentUnit(c)
+	assignment := h.experiments.Assign(model.ExperimentSearch, unitType, unitID)
+	if assignment != nil {
+		query.Ranking = &assignment.Params
+	}
+
+	// Execute search
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	started := time.Now()
+
+	userID := currentUser(c).UserID()
+	hideInstalled := c.QueryBool("hide_installed")
+
+	var profile *analytics.UserProfile
+	if userID != "" && (h.cfg.EnablePersonalization || hideInstalled) {
+		var err error
+		profile, err = h.personalizer.Profile(ctx, userID)
+		if err != nil {
+			// Fall back to the shared ranking
+			log.Printf("Search personalization error: %v", err)
+		}
+	}
+	if profile != nil && hideInstalled {
+		query.ExcludeIDs = profile.Installed
+	}
+
+	// Re-ranking only reorders relevance results, and needs the whole
+	// window, so fetch it from the top
+	depth := h.cfg.PersonalizationDepth
+	rerank := h.cfg.EnablePersonalization && profile != nil && !profile.Empty() &&
+		query.Order() == search.SortRelevance && query.Offset < depth
+	esQuery := query
+	if rerank {
+		esQuery.Offset = 0
+		esQuery.Limit = depth
+		if query.Offset+query.Limit > depth {
+			esQuery.Limit = query.Offset + query.Limit
+		}
+	}
+
+	result, err := h.searchService.Search(ctx, esQuery)
+	if err != nil {
+		log.Printf("Search error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Search failed",
+		})
+	}
+
+	if rerank {
+		window := result.Servers
+		if len(window) > depth {
+			window = window[:depth]
+		}
+		result.Personalized = profile.Rerank(window, h.cfg.PersonalizationWeight)
+		result.Servers = pageOf(result.Servers, query.Offset, query.Limit)
+	}
+	latency := time.Since(started)
+
+	result.SearchID = utils.UUIDv4()
+	result.Experiment = assignment
+
+	resultIDs := make([]string, len(result.Servers))
+	for i, server := range result.Servers {
+		resultIDs[i] = server.ID
+	}
+
+	entry := &model.SearchLog{
+		ID:           result.SearchID,
+		UserID:       userID,
+		Query:        query.Query,
+		Normalized:   analytics.NormalizeQuery(query.Query),
+		Filters:      query.Filters,
+		Sort:         query.Sort,
+		Offset:       query.Offset,
+		Limit:        query.Limit,
+		ResultCount:  result.Total,
+		ResultIDs:    resultIDs,
+		Personalized: result.Personalized,
+		LatencyMs:    latency.Milliseconds(),
+		CreatedAt:    started.UTC(),
+	}
+	if assignment != nil {
+		entry.ExperimentID = assignment.ExperimentID
+		entry.Variant = assignment.Variant
+	}
+	h.searchAnalytics.LogSearch(ctx, entry)
+
+	return c.JSON(result)
+}
+
+// TrackEvent attributes a click or install to a result of a search from the
+// last day. The server must have been returned at the given position.
+func (h *SearchHandler) TrackEvent(c *fiber.Ctx) error {
+	var req searchEventRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if req.SearchID == "" || len(req.SearchID) > maxInstallFieldLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "search_id is required",
+		})
+	}
+	if req.ServerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "server_id is required",
+		})
+	}
+	if req.Type != model.SearchEventClick && req.Type != model.SearchEventInstall {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "type must be 'click' or 'install'",
+		})
+	}
+	if req.Position < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "position must be at least 1",
+		})
+	}
+
+	event := &model.SearchEvent{
+		SearchID:  req.SearchID,
+		ServerID:  req.ServerID,
+		Type:      req.Type,
+		Position:  req.Position,
+		UserID:    currentUser(c).UserID(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	// Only attribute events to results the search actually returned
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	err := h.searchAnalytics.VerifyEvent(ctx, event)
+	switch {
+	case errors.Is(err, analytics.ErrInvalidSearchEvent):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "server_id was not returned at that position by the search",
+		})
+	case err != nil:
+		log.Printf("Search event verification error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record search event",
+		})
+	}
+
+	h.searchAnalytics.RecordEvent(event)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status": "accepted",
+	})
+}
+
+// TopQueries returns the most frequent search queries
+func (h *SearchHandler) TopQueries(c *fiber.Ctx) error {
+	return h.queryReport(c, false)
+}
+
+// ZeroResultQueries returns the most frequent queries that found nothing
+func (h *SearchHandler) ZeroResultQueries(c *fiber.Ctx) error {
+	return h.queryReport(c, true)
+}
+
+// ClickThrough returns click-through and install rates by result position
+func (h *SearchHandler) ClickThrough(c *fiber.Ctx) error {
+	since, err := reportSince(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	positions, err := h.searchAnalytics.ClickThrough(ctx, since, maxReportedPosition)
+	if err != nil {
+		log.Printf("Search CTR report error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load click-through report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"since":     since,
+		"positions": positions,
+	})
+}
+
+// queryReport serves the top or zero-result query report
+func (h *SearchHandler) queryReport(c *fiber.Ctx, zeroResultsOnly bool) error {
+	since, err := reportSince(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	_, limit := pageParams(c, 50, maxSearchReportLimit)
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	queries, err := h.searchAnalytics.TopQueries(ctx, since, zeroResultsOnly, limit)
+	if err != nil {
+		log.Printf("Search query report error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load query report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"since":   since,
+		"queries": queries,
+	})
+}
+
+// reportSince reads the days query parameter as the start of a report window
+func reportSince(c *fiber.Ctx) (time.Time, error) {
+	days := c.QueryInt("days", 7)
+	if days < 1 || days > maxSearchReportDays {
+		return time.Time{}, fmt.Errorf("days must be between 1 and %d", maxSearchReportDays)
+	}
+	return time.Now().UTC().AddDate(0, 0, -days), nil
+}
+
+// pageOf returns the page of servers at offset, at most limit long
+func pageOf(servers []model.ServerDetail, offset, limit int) []model.ServerDetail {
+	if offset < 0 || offset >= len(servers) {
+		return []model.ServerDetail{}
+	}
+	servers = servers[offset:]
+	if limit >= 0 && len(servers) > limit {
+		servers = servers[:limit]
+	}
+	return servers
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/cache"
+	"github.com/pluggedin/mcp-analytics/internal/config"
+)
+
+// Cache keys of the platform statistics
+const (
+	statsGlobalKey     = "stats:global"
+	statsCategoriesKey = "stats:categories"
+	statsToolsKey      = "stats:tools"
+)
+
+// StatsHandler serves platform-wide statistics
+type StatsHandler struct {
+	stats *analytics.PlatformStats
+	cache *cache.Cache
+	cfg   *config.Config
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(stats *analytics.PlatformStats, cache *cache.Cache, cfg *config.Config) *StatsHandler {
+	return &StatsHandler{
+		stats: stats,
+		cache: cache,
+		cfg:   cfg,
+	}
+}
+
+// Global returns platform totals, breakdowns and 30-day growth
+func (h *StatsHandler) Global(c *fiber.Ctx) error {
+	return h.serve(c, statsGlobalKey)
+}
+
+// Categories returns per-category statistics
+func (h *StatsHandler) Categories(c *fiber.Ctx) error {
+	return h.serve(c, statsCategoriesKey)
+}
+
+// Tools returns the most used and most declared tools
+func (h *StatsHandler) Tools(c *fiber.Ctx) error {
+	return h.serve(c, statsToolsKey)
+}
+
+// Refresh recomputes every statistic and replaces the cached copies, so
+// requests are served from the cache between runs
+func (h *StatsHandler) Refresh(ctx context.Context) error {
+	ttl := time.Duration(h.cfg.StatsCacheTTL) * time.Second
+	computers := h.computers()
+
+	var failed int
+	for key, compute := range computers {
+		value, err := compute(ctx)
+		if err == nil {
+			err = h.cache.Set(ctx, key, value, ttl)
+		}
+		if err != nil {
+			log.Printf("Failed to refresh %s: %v", key, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d statistics failed to refresh", failed, len(computers))
+	}
+
+	return nil
+}
+
+// computers maps each statistic's cache key to the function computing it
+func (h *StatsHandler) computers() map[string]func(context.Context) (interface{}, error) {
+	return map[string]func(context.Context) (interface{}, error){
+		statsGlobalKey: func(ctx context.Context) (interface{}, error) {
+			return h.stats.Global(ctx)
+		},
+		statsCategoriesKey: func(ctx context.Context) (interface{}, error) {
+			categories, err := h.stats.Categories(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return fiber.Map{"categories": categories, "total": len(categories)}, nil
+		},
+		statsToolsKey: func(ctx context.Context) (interface{}, error) {
+			return h.stats.Tools(ctx)
+		},
+	}
+}
+
+// serve returns a cached statistic, computing it on a cache miss
+func (h *StatsHandler) serve(c *fiber.Ctx, key string) error {
+	compute := h.computers()[key]
+
+	ctx, cancel := context.WithTimeout(c.Context(), 10*time.Second)
+	defer cancel()
+
+	ttl := time.Duration(h.cfg.StatsCacheTTL) * time.Second
+
+	var stats map[string]interface{}
+	err := h.cache.Remember(ctx, key, ttl, &stats, func() (interface{}, error) {
+		return compute(ctx)
+	})
+	if err != nil {
+		log.Printf("Stats %s error: %v", key, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load statistics",
+		})
+	}
+
+	return c.JSON(stats)
+}
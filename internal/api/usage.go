@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/analytics"
+	"github.com/pluggedin/mcp-analytics/internal/model"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// Limits for usage event batches
+const (
+	maxUsageEventsPerRequest = 500
+	maxUsageEventAge         = 7 * 24 * time.Hour
+	maxUsageClockSkew        = 5 * time.Minute
+)
+
+// UsageHandler ingests tool call, prompt use and template use events
+type UsageHandler struct {
+	buffer        *analytics.UsageBuffer
+	activeUsers   *analytics.ActiveUsers
+	searchService *search.Service
+}
+
+// usageRequest is the payload for POST /v1/usage
+type usageRequest struct {
+	ServerID string              `json:"server_id"`
+	Events   []usageEventRequest `json:"events"`
+}
+
+// usageEventRequest is a single event within a usage batch
+type usageEventRequest struct {
+	Type       string                 `json:"type"`
+	Name       string                 `json:"name"`
+	Timestamp  time.Time              `json:"timestamp"`
+	DurationMs int64                  `json:"duration_ms"`
+	Success    *bool                  `json:"success"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// rejectedUsageEvent explains why an event in a batch was not accepted
+type rejectedUsageEvent struct {
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(buffer *analytics.UsageBuffer, activeUsers *analytics.ActiveUsers, searchService *search.Service) *UsageHandler {
+	return &UsageHandler{
+		buffer:        buffer,
+		activeUsers:   activeUsers,
+		searchService: searchService,
+	}
+}
+
+// Track validates a batch of usage events and queues the valid ones for storage
+func (h *UsageHandler) Track(c *fiber.Ctx) error {
+	var req usageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request format",
+		})
+	}
+
+	if req.ServerID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "server_id is required",
+		})
+	}
+	if len(req.Events) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "events must not be empty",
+		})
+	}
+	if len(req.Events) > maxUsageEventsPerRequest {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": fmt.Sprintf("at most %d events per request", maxUsageEventsPerRequest),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+	defer cancel()
+
+	server, err := h.searchService.GetServer(ctx, req.ServerID)
+	if err != nil {
+		return serverLookupError(c, err)
+	}
+
+	userID := currentUser(c).UserID()
+	now := time.Now().UTC()
+
+	events := make([]model.UsageEvent, 0, len(req.Events))
+	rejected := []rejectedUsageEvent{}
+	for i, item := range req.Events {
+		if reason := validateUsageEvent(server, item, now); reason != "" {
+			rejected = append(rejected, rejectedUsageEvent{Index: i, Reason: reason})
+			continue
+		}
+
+		event := model.UsageEvent{
+			ServerID:   server.ID,
+			UserID:     userID,
+			Type:       item.Type,
+			Name:       item.Name,
+			Timestamp:  item.Timestamp.UTC(),
+			DurationMs: item.DurationMs,
+			Success:    item.Success == nil || *item.Success,
+			Metadata:   item.Metadata,
+		}
+		if item.Timestamp.IsZero() {
+			event.Timestamp = now
+		}
+		events = append(events, event)
+	}
+
+	if len(events) > 0 {
+		if err := h.buffer.Add(events...); err != nil {
+			if errors.Is(err, analytics.ErrUsageBufferFull) {
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"error": "Usage buffer full, retry later",
+				})
+			}
+			log.Printf("Usage buffer error: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to record usage",
+			})
+		}
+
+		// Count the user as active on each day the batch covers
+		days := make(map[string]time.Time)
+		for _, event := range events {
+			days[event.Timestamp.Format("2006-01-02")] = event.Timestamp
+		}
+		for _, day := range days {
+			h.activeUsers.TrackQuietly(ctx, server.ID, userID, day)
+		}
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"accepted": len(events),
+		"rejected": rejected,
+	})
+}
+
+// validateUsageEvent returns why an event is invalid, or an empty string
+func validateUsageEvent(server *model.ServerDetail, event usageEventRequest, now time.Time) string {
+	switch event.Type {
+	case model.UsageToolCall, model.UsagePromptUse, model.UsageTemplateUse:
+	default:
+		return "type must be 'tool_call', 'prompt_use' or 'template_use'"
+	}
+
+	if event.Name == "" {
+		return "name is required"
+	}
+	if !server.HasCapability(event.Type, event.Name) {
+		return fmt.Sprintf("server does not declare %s '%s'", capabilityKind(event.Type), event.Name)
+	}
+
+	if event.DurationMs < 0 {
+		return "duration_ms must not be negative"
+	}
+
+	if !event.Timestamp.IsZero() {
+		if event.Timestamp.After(now.Add(maxUsageClockSkew)) {
+			return "timestamp is in the future"
+		}
+		if event.Timestamp.Before(now.Add(-maxUsageEventAge)) {
+			return "timestamp is too old"
+		}
+	}
+
+	return ""
+}
+
+// capabilityKind names the server capability a usage event type refers to
+func capabilityKind(eventType string) string {
+	switch eventType {
+	case model.UsagePromptUse:
+		return "prompt"
+	case model.UsageTemplateUse:
+		return "template"
+	default:
+		return "tool"
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pluggedin/mcp-analytics/internal/search"
+)
+
+// pagination describes a page of results in list responses
+type pagination struct {
+	Page         int `json:"page"`
+	Limit        int `json:"limit"`
+	TotalResults int `json:"total_results"`
+	TotalPages   int `json:"total_pages"`
+}
+
+// newPagination builds the pagination block for a page of total results
+func newPagination(page, limit, total int) pagination {
+	return pagination{
+		Page:         page,
+		Limit:        limit,
+		TotalResults: total,
+		TotalPages:   (total + limit - 1) / limit,
+	}
+}
+
+// pageParams reads the 1-based page and limit query parameters
+func pageParams(c *fiber.Ctx, defaultLimit, maxLimit int) (page, limit int) {
+	page = c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+
+	limit = c.QueryInt("limit", defaultLimit)
+	if limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	return page, limit
+}
+
+// serverIDParam returns the decoded :id path parameter. Server IDs contain
+// slashes, so clients send them URL-encoded.
+func serverIDParam(c *fiber.Ctx) (string, error) {
+	id, err := url.PathUnescape(c.Params("id"))
+	if err != nil || id == "" {
+		return "", errors.New("invalid server ID")
+	}
+	return id, nil
+}
+
+// serverLookupError maps a failed server lookup to a response
+func serverLookupError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, search.ErrServerNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Server not found",
+		})
+	}
+
+	log.Printf("Server lookup error: %v", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to look up server",
+	})
+}
+
+// metadataString returns a string value from a metadata map
+func metadataString(metadata map[string]interface{}, key string) string {
+	value, _ := metadata[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// truncate limits s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache is a JSON cache backed by Redis
+type Cache struct {
+	client *redis.Client
+}
+
+// New creates a new Redis-backed cache
+func New(redisURL string) (*Cache, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	log.Println("Connected to Redis")
+
+	return &Cache{client: client}, nil
+}
+
+// Client returns the underlying Redis client for data structures beyond
+// JSON values
+func (c *Cache) Client() *redis.Client {
+	return c.client
+}
+
+// Get loads a cached value into dest. It reports false when the key is missing.
+func (c *Cache) Get(ctx context.Context, key string, dest interface{}) (bool, error) {
+	data, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get cache key %s: %w", key, err)
+	}
+
+	if err := json.Unmarshal(data, dest); err != nil {
+		return false, fmt.Errorf("failed to decode cache key %s: %w", key, err)
+	}
+
+	return true, nil
+}
+
+// Set stores a value under key for the given TTL
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache key %s: %w", key, err)
+	}
+
+	if err := c.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set cache key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// Remember returns the cached value for key, or calls load, caches its result
+// and returns it. Cache errors are logged and never fail the request.
+func (c *Cache) Remember(ctx context.Context, key string, ttl time.Duration, dest interface{}, load func() (interface{}, error)) error {
+	found, err := c.Get(ctx, key, dest)
+	if err != nil {
+		log.Printf("Cache read warning: %v", err)
+	}
+	if found {
+		return nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return err
+	}
+
+	if err := c.Set(ctx, key, value, ttl); err != nil {
+		log.Printf("Cache write warning: %v", err)
+	}
+
+	// Round-trip through JSON so dest is populated the same way on hits and misses
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+// DeletePrefix removes every key starting with prefix
+func (c *Cache) DeletePrefix(ctx context.Context, prefix string) error {
+	iter := c.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := c.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return fmt.Errorf("failed to delete cache key %s: %w", iter.Val(), err)
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan cache keys: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying Redis connection
+func (c *Cache) Close() error {
+	return c.client.Close()
+}
@@ -28,6 +28,10 @@ type Config struct {
 	// CORS configuration
 	CORSOrigins string `env:"CORS_ORIGINS" envDefault:"http://localhost:3000,http://localhost:3001"`
 
+	// Reverse proxies (IPs or CIDRs) whose X-Forwarded-For header carries the
+	// client IP
+	TrustedProxies string `env:"TRUSTED_PROXIES" envDefault:""`
+
 	// Cache configuration
 	CacheTTL                int `env:"CACHE_TTL" envDefault:"300"`        // 5 minutes
 	SearchCacheTTL          int `env:"SEARCH_CACHE_TTL" envDefault:"300"` // 5 minutes
@@ -45,6 +49,8 @@ type Config struct {
 	EnableRealTimeAnalytics bool `env:"ENABLE_REAL_TIME_ANALYTICS" envDefault:"true"`
 	EnableSearchSuggestions bool `env:"ENABLE_SEARCH_SUGGESTIONS" envDefault:"true"`
 	EnableWebSocket         bool `env:"ENABLE_WEBSOCKET" envDefault:"true"`
+	EnableSSE               bool `env:"ENABLE_SSE" envDefault:"true"`
+	EnablePersonalization   bool `env:"ENABLE_PERSONALIZATION" envDefault:"true"`
 
 	// Monitoring
 	PrometheusEnabled bool   `env:"PROMETHEUS_ENABLED" envDefault:"false"`
@@ -64,6 +70,22 @@ type Config struct {
 	SearchDefaultLimit  int `env:"SEARCH_DEFAULT_LIMIT" envDefault:"20"`
 	SearchMinQueryLen   int `env:"SEARCH_MIN_QUERY_LEN" envDefault:"2"`
 
+	// Personalized search: the top results re-ranked for signed-in users and
+	// the share of the order decided by the user's profile
+	PersonalizationDepth  int     `env:"PERSONALIZATION_DEPTH" envDefault:"50"`
+	PersonalizationWeight float64 `env:"PERSONALIZATION_WEIGHT" envDefault:"0.3"`
+
+	// Realtime updates: the connection limit, the events queued per client
+	// before a slow client is dropped, and the recent events kept for
+	// clients resuming an event stream
+	RealtimeMaxConnections int `env:"REALTIME_MAX_CONNECTIONS" envDefault:"10000"`
+	RealtimeClientBuffer   int `env:"REALTIME_CLIENT_BUFFER" envDefault:"64"`
+	RealtimeReplayBuffer   int `env:"REALTIME_REPLAY_BUFFER" envDefault:"1000"`
+
+	// InstanceID identifies this replica when relaying realtime events between
+	// replicas through Redis. Defaults to the hostname and a random suffix.
+	InstanceID string `env:"INSTANCE_ID" envDefault:""`
+
 	// Analytics configuration
 	TrendingPeriodHours int     `env:"TRENDING_PERIOD_HOURS" envDefault:"168"` // 7 days
 	TrendingMinInstalls int     `env:"TRENDING_MIN_INSTALLS" envDefault:"10"`
@@ -124,6 +146,16 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("Registry URL is required")
 	}
 
+	if c.PersonalizationWeight < 0 || c.PersonalizationWeight > 1 {
+		return fmt.Errorf("personalization weight must be between 0 and 1: %v", c.PersonalizationWeight)
+	}
+	if c.RealtimeClientBuffer < 1 {
+		return fmt.Errorf("realtime client buffer must be positive: %d", c.RealtimeClientBuffer)
+	}
+	if c.RealtimeReplayBuffer < 1 {
+		return fmt.Errorf("realtime replay buffer must be positive: %d", c.RealtimeReplayBuffer)
+	}
+
 	// Validate API keys in production
 	if c.Environment == "production" {
 		if c.InternalAPIKey == "" || c.InternalAPIKey == "dev-internal-key" {
@@ -151,6 +183,17 @@ func (c *Config) GetCORSOrigins() []string {
 	return origins
 }
 
+// GetTrustedProxies returns the trusted proxies as a slice
+func (c *Config) GetTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 // IsDevelopment returns true if running in development mode
 func (c *Config) IsDevelopment() bool {
 	return c.Environment == "development"
@@ -0,0 +1,181 @@
+package database
+
+// migrations holds the PostgreSQL schema changes in the order they are applied.
+// Released entries must never be edited; append a new migration instead.
+var migrations = []string{
+	// 1: admin-curated featured servers
+	`CREATE TABLE featured_servers (
+		id            BIGSERIAL PRIMARY KEY,
+		server_id     TEXT NOT NULL,
+		category      TEXT NOT NULL DEFAULT '',
+		blurb         TEXT NOT NULL DEFAULT '',
+		display_order INTEGER NOT NULL DEFAULT 0,
+		starts_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		ends_at       TIMESTAMPTZ,
+		archived_at   TIMESTAMPTZ,
+		created_by    TEXT NOT NULL DEFAULT '',
+		created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CHECK (ends_at IS NULL OR ends_at > starts_at)
+	);
+	CREATE INDEX featured_servers_active_idx ON featured_servers (category, display_order)
+		WHERE archived_at IS NULL;`,
+
+	// 2: install and uninstall tracking, one active install per user/server
+	`CREATE TABLE user_installs (
+		id                 BIGSERIAL PRIMARY KEY,
+		user_id            TEXT NOT NULL,
+		server_id          TEXT NOT NULL,
+		platform           TEXT NOT NULL DEFAULT '',
+		app_version        TEXT NOT NULL DEFAULT '',
+		package_type       TEXT NOT NULL DEFAULT '',
+		source             TEXT NOT NULL DEFAULT '',
+		referrer           TEXT NOT NULL DEFAULT '',
+		metadata           JSONB NOT NULL DEFAULT '{}',
+		installed_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		uninstalled_at     TIMESTAMPTZ,
+		uninstall_reason   TEXT NOT NULL DEFAULT '',
+		uninstall_feedback TEXT NOT NULL DEFAULT '',
+		updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX user_installs_active_idx ON user_installs (user_id, server_id)
+		WHERE uninstalled_at IS NULL;
+	CREATE INDEX user_installs_server_idx ON user_installs (server_id, installed_at);
+	CREATE INDEX user_installs_updated_idx ON user_installs (updated_at);`,
+
+	// 3: ratings with optional reviews, one per user/server, plus helpfulness votes
+	`CREATE TABLE user_ratings (
+		id               BIGSERIAL PRIMARY KEY,
+		user_id          TEXT NOT NULL,
+		server_id        TEXT NOT NULL,
+		rating           SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+		title            TEXT NOT NULL DEFAULT '',
+		content          TEXT NOT NULL DEFAULT '',
+		pros             TEXT[] NOT NULL DEFAULT '{}',
+		cons             TEXT[] NOT NULL DEFAULT '{}',
+		would_recommend  BOOLEAN,
+		verified_install BOOLEAN NOT NULL DEFAULT FALSE,
+		helpful_count    INTEGER NOT NULL DEFAULT 0,
+		unhelpful_count  INTEGER NOT NULL DEFAULT 0,
+		created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (user_id, server_id)
+	);
+	CREATE INDEX user_ratings_server_idx ON user_ratings (server_id, created_at DESC);
+	CREATE TABLE review_votes (
+		rating_id  BIGINT NOT NULL REFERENCES user_ratings (id) ON DELETE CASCADE,
+		user_id    TEXT NOT NULL,
+		helpful    BOOLEAN NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (rating_id, user_id)
+	);`,
+
+	// 4: review moderation; existing reviews are grandfathered as approved
+	`ALTER TABLE user_ratings
+		ADD COLUMN status TEXT NOT NULL DEFAULT 'approved'
+			CHECK (status IN ('pending', 'approved', 'rejected', 'flagged')),
+		ADD COLUMN moderation_reasons TEXT[] NOT NULL DEFAULT '{}',
+		ADD COLUMN flag_count INTEGER NOT NULL DEFAULT 0,
+		ADD COLUMN moderated_by TEXT NOT NULL DEFAULT '',
+		ADD COLUMN moderated_at TIMESTAMPTZ;
+	ALTER TABLE user_ratings ALTER COLUMN status SET DEFAULT 'pending';
+	CREATE INDEX user_ratings_status_idx ON user_ratings (status, created_at);
+	CREATE INDEX user_ratings_content_idx ON user_ratings
+		(MD5(LOWER(BTRIM(REGEXP_REPLACE(content, '\s+', ' ', 'g')))));
+	CREATE INDEX user_installs_user_idx ON user_installs (user_id, installed_at);
+	CREATE TABLE review_flags (
+		rating_id  BIGINT NOT NULL REFERENCES user_ratings (id) ON DELETE CASCADE,
+		user_id    TEXT NOT NULL,
+		reason     TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (rating_id, user_id)
+	);`,
+
+	// 5: server detail views for discovery funnel attribution
+	`CREATE TABLE server_views (
+		id         BIGSERIAL PRIMARY KEY,
+		server_id  TEXT NOT NULL,
+		user_id    TEXT NOT NULL DEFAULT '',
+		surface    TEXT NOT NULL,
+		source     TEXT NOT NULL DEFAULT '',
+		referrer   TEXT NOT NULL DEFAULT '',
+		position   INTEGER,
+		search_id  TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX server_views_server_idx ON server_views (server_id, created_at);
+	CREATE INDEX server_views_created_idx ON server_views (created_at);
+	CREATE INDEX user_installs_installed_idx ON user_installs (installed_at);`,
+
+	// 6: install fraud detection; flagged installs are excluded from scoring
+	`ALTER TABLE user_installs
+		ADD COLUMN ip_address TEXT NOT NULL DEFAULT '',
+		ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+		ADD COLUMN flagged BOOLEAN NOT NULL DEFAULT FALSE;
+	CREATE INDEX user_installs_flagged_idx ON user_installs (id) WHERE flagged;
+	CREATE TABLE fraud_flags (
+		id                BIGSERIAL PRIMARY KEY,
+		subject_type      TEXT NOT NULL CHECK (subject_type IN ('server', 'user')),
+		subject_id        TEXT NOT NULL,
+		rule              TEXT NOT NULL,
+		status            TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed')),
+		evidence          JSONB NOT NULL DEFAULT '[]',
+		install_ids       BIGINT[] NOT NULL DEFAULT '{}',
+		first_detected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		last_detected_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		reviewed_by       TEXT NOT NULL DEFAULT '',
+		reviewed_at       TIMESTAMPTZ,
+		UNIQUE (subject_type, subject_id, rule)
+	);
+	CREATE INDEX fraud_flags_status_idx ON fraud_flags (status, subject_type, last_detected_at DESC);`,
+
+	// 7: webhooks notified of a server's usage anomaly alerts
+	`CREATE TABLE alert_webhooks (
+		id               BIGSERIAL PRIMARY KEY,
+		user_id          TEXT NOT NULL,
+		server_id        TEXT NOT NULL,
+		url              TEXT NOT NULL,
+		secret           TEXT NOT NULL,
+		active           BOOLEAN NOT NULL DEFAULT TRUE,
+		failure_count    INTEGER NOT NULL DEFAULT 0,
+		last_error       TEXT NOT NULL DEFAULT '',
+		last_delivery_at TIMESTAMPTZ,
+		created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (user_id, server_id, url)
+	);
+	CREATE INDEX alert_webhooks_server_idx ON alert_webhooks (server_id) WHERE active;`,
+
+	// 8: ranking experiments, the units exposed to them, and sessions on
+	// views so anonymous units can be followed
+	`CREATE TABLE experiments (
+		id          BIGSERIAL PRIMARY KEY,
+		key         TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		surface     TEXT NOT NULL,
+		status      TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'running', 'stopped')),
+		variants    JSONB NOT NULL,
+		created_by  TEXT NOT NULL DEFAULT '',
+		started_at  TIMESTAMPTZ,
+		stopped_at  TIMESTAMPTZ,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX experiments_running_idx ON experiments (surface) WHERE status = 'running';
+	CREATE TABLE experiment_exposures (
+		experiment_id    BIGINT NOT NULL REFERENCES experiments (id) ON DELETE CASCADE,
+		unit_type        TEXT NOT NULL CHECK (unit_type IN ('user', 'session')),
+		unit_id          TEXT NOT NULL,
+		variant          TEXT NOT NULL,
+		exposures        BIGINT NOT NULL DEFAULT 1,
+		first_exposed_at TIMESTAMPTZ NOT NULL,
+		last_exposed_at  TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (experiment_id, unit_type, unit_id)
+	);
+	ALTER TABLE server_views ADD COLUMN session_id TEXT NOT NULL DEFAULT '';
+	CREATE INDEX server_views_user_idx ON server_views (user_id, created_at) WHERE user_id <> '';
+	CREATE INDEX server_views_session_idx ON server_views (session_id, created_at) WHERE session_id <> '';`,
+
+	// 9: installs an admin has cleared, so dismissals cover only what was reviewed
+	`ALTER TABLE fraud_flags ADD COLUMN reviewed_install_ids BIGINT[] NOT NULL DEFAULT '{}';
+	UPDATE fraud_flags SET reviewed_install_ids = install_ids WHERE status = 'dismissed';`,
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// ActiveUsers holds the distinct active users of a server, or of all servers
+// when ServerID is empty, for the day and the 7 and 30 days ending on Date
+type ActiveUsers struct {
+	ServerID  string    `json:"server_id,omitempty" bson:"server_id"`
+	Date      time.Time `json:"date" bson:"date"`
+	DAU       int64     `json:"dau" bson:"dau"`
+	WAU       int64     `json:"wau" bson:"wau"`
+	MAU       int64     `json:"mau" bson:"mau"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
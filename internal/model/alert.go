@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Usage alert kinds
+const (
+	AlertVolumeZero      = "volume_zero"
+	AlertVolumeDrop      = "volume_drop"
+	AlertSuccessRateDrop = "success_rate_drop"
+)
+
+// Usage alert severities and states
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+
+	AlertOpen     = "open"
+	AlertResolved = "resolved"
+)
+
+// UsageAlert records an anomaly in a server's hourly usage, for the whole
+// server or a single capability. An alert stays open while the anomaly
+// persists and is resolved by the first normal hour.
+type UsageAlert struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ServerID       string             `json:"server_id" bson:"server_id"`
+	Kind           string             `json:"kind" bson:"kind"`
+	Severity       string             `json:"severity" bson:"severity"`
+	Status         string             `json:"status" bson:"status"`
+	CapabilityType string             `json:"capability_type,omitempty" bson:"capability_type"` // empty for the whole server
+	Capability     string             `json:"capability,omitempty" bson:"capability"`
+	Observed       float64            `json:"observed" bson:"observed"`
+	Expected       float64            `json:"expected" bson:"expected"`
+	ZScore         float64            `json:"z_score" bson:"z_score"`
+	FirstHour      time.Time          `json:"first_hour" bson:"first_hour"`
+	LastHour       time.Time          `json:"last_hour" bson:"last_hour"`
+	ResolvedAt     *time.Time         `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// AlertWebhook is a URL notified when a server's alerts open or resolve
+type AlertWebhook struct {
+	ID             int64      `json:"id"`
+	UserID         string     `json:"user_id"`
+	ServerID       string     `json:"server_id"`
+	URL            string     `json:"url"`
+	Secret         string     `json:"secret,omitempty"` // only returned on creation
+	Active         bool       `json:"active"`
+	FailureCount   int        `json:"failure_count"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastDeliveryAt *time.Time `json:"last_delivery_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"time"
+)
+
+// Experiment states
+const (
+	ExperimentDraft   = "draft"
+	ExperimentRunning = "running"
+	ExperimentStopped = "stopped"
+)
+
+// Surfaces whose ranking experiments can change
+const (
+	ExperimentSearch   = "search"
+	ExperimentTrending = "trending"
+	ExperimentTopRated = "top_rated"
+	ExperimentRecent   = "recent"
+)
+
+// ExperimentSurfaces lists the surfaces experiments can run on
+var ExperimentSurfaces = []string{ExperimentSearch, ExperimentTrending, ExperimentTopRated, ExperimentRecent}
+
+// Units that experiments assign variants to
+const (
+	UnitUser    = "user"
+	UnitSession = "session"
+)
+
+// RankingParams adjust how search and the discovery lists rank servers.
+// Zero values keep the default ranking.
+type RankingParams struct {
+	// FieldBoosts replaces the text fields searched and their boosts
+	FieldBoosts map[string]float64 `json:"field_boosts,omitempty"`
+	// ScoreBoosts adds factor * log(1 + field) of numeric fields to relevance
+	ScoreBoosts map[string]float64 `json:"score_boosts,omitempty"`
+	// Sort is the search order when none is requested, or the discovery
+	// list order
+	Sort string `json:"sort,omitempty"`
+}
+
+// Experiment tests ranking variants on a surface. Units are split between
+// variants in proportion to their weights; the first variant is the control.
+type Experiment struct {
+	ID          int64               `json:"id"`
+	Key         string              `json:"key"`
+	Description string              `json:"description,omitempty"`
+	Surface     string              `json:"surface"`
+	Status      string              `json:"status"`
+	Variants    []ExperimentVariant `json:"variants"`
+	CreatedBy   string              `json:"created_by,omitempty"`
+	StartedAt   *time.Time          `json:"started_at,omitempty"`
+	StoppedAt   *time.Time          `json:"stopped_at,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// ExperimentVariant is one arm of an experiment
+type ExperimentVariant struct {
+	Name   string        `json:"name"`
+	Weight int           `json:"weight"`
+	Params RankingParams `json:"params"`
+}
+
+// ExperimentAssignment is the variant a unit sees in a running experiment
+type ExperimentAssignment struct {
+	ExperimentID int64         `json:"experiment_id"`
+	Key          string        `json:"key"`
+	Variant      string        `json:"variant"`
+	Params       RankingParams `json:"-"`
+}
+
+// What click-through measures in an experiment report
+const (
+	ClickThroughSearchClicks = "search_clicks"
+	ClickThroughServerViews  = "server_views"
+)
+
+// ExperimentReport compares the variants of an experiment
+type ExperimentReport struct {
+	Experiment         Experiment      `json:"experiment"`
+	To                 time.Time       `json:"to"`
+	Confidence         float64         `json:"confidence"`
+	ClickThroughMetric string          `json:"click_through_metric"`
+	Variants           []VariantReport `json:"variants"`
+}
+
+// VariantReport measures the units exposed to a variant. For search
+// experiments click-through is the share of the variant's searches with a
+// result clicked; on discovery surfaces it is the share of units that went
+// on to view a server. Install conversion is the share of units that went on
+// to install one.
+type VariantReport struct {
+	Variant           string          `json:"variant"`
+	Control           bool            `json:"control,omitempty"`
+	Units             int64           `json:"units"`
+	Exposures         int64           `json:"exposures"`
+	Searches          int64           `json:"searches,omitempty"`
+	ClickedSearches   int64           `json:"clicked_searches,omitempty"`
+	ClickedUnits      int64           `json:"clicked_units,omitempty"`
+	InstalledUnits    int64           `json:"installed_units"`
+	ClickThrough      RateEstimate    `json:"click_through"`
+	InstallConversion RateEstimate    `json:"install_conversion"`
+	ClickThroughDiff  *RateDifference `json:"click_through_diff,omitempty"`      // vs the control
+	InstallDiff       *RateDifference `json:"install_conversion_diff,omitempty"` // vs the control
+}
+
+// RateEstimate is a proportion with its confidence interval
+type RateEstimate struct {
+	Rate  float64 `json:"rate"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// RateDifference is the difference between a variant's and the control's
+// proportions with its confidence interval. It is significant when the
+// interval excludes zero.
+type RateDifference struct {
+	Difference  float64 `json:"difference"`
+	Lower       float64 `json:"lower"`
+	Upper       float64 `json:"upper"`
+	Significant bool    `json:"significant"`
+}
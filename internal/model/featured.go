@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// FeaturedServer represents an admin-curated featured placement
+type FeaturedServer struct {
+	ID           int64      `json:"id"`
+	ServerID     string     `json:"server_id"`
+	Category     string     `json:"category,omitempty"` // empty means featured globally
+	Blurb        string     `json:"blurb,omitempty"`
+	DisplayOrder int        `json:"display_order"`
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at,omitempty"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	CreatedBy    string     `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Live server data (populated when serving the public list)
+	Server *ServerDetail `json:"server,omitempty"`
+}
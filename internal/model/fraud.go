@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// FraudFlag records suspicious install activity by a server or an account.
+// Installs covered by open flags are excluded from counts and scoring.
+type FraudFlag struct {
+	ID              int64                    `json:"id"`
+	SubjectType     string                   `json:"subject_type"` // server or user
+	SubjectID       string                   `json:"subject_id"`
+	Rule            string                   `json:"rule"`
+	Status          string                   `json:"status"`
+	Evidence        []map[string]interface{} `json:"evidence"`
+	InstallCount    int                      `json:"install_count"`
+	FirstDetectedAt time.Time                `json:"first_detected_at"`
+	LastDetectedAt  time.Time                `json:"last_detected_at"`
+	ReviewedBy      string                   `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time               `json:"reviewed_at,omitempty"`
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+)
+
+// Discovery surfaces that lead users to a server
+const (
+	SurfaceSearch   = "search"
+	SurfaceTrending = "trending"
+	SurfaceFeatured = "featured"
+	SurfaceSimilar  = "similar"
+	SurfaceDirect   = "direct"
+	SurfaceOther    = "other"
+)
+
+// Surfaces lists the discovery surfaces in display order
+var Surfaces = []string{SurfaceSearch, SurfaceTrending, SurfaceFeatured, SurfaceSimilar, SurfaceDirect, SurfaceOther}
+
+// ServerView records a user opening a server's detail page
+type ServerView struct {
+	ID        int64     `json:"id"`
+	ServerID  string    `json:"server_id"`
+	UserID    string    `json:"user_id,omitempty"`
+	Surface   string    `json:"surface"`
+	Source    string    `json:"source,omitempty"`
+	Referrer  string    `json:"referrer,omitempty"`
+	Position  *int      `json:"position,omitempty"`
+	SearchID  string    `json:"search_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FunnelStage holds the counts and conversion rates of one discovery funnel.
+// Impressions and clicks are only known for search.
+type FunnelStage struct {
+	Surface           string   `json:"surface"`
+	Impressions       int64    `json:"impressions,omitempty"`
+	Clicks            int64    `json:"clicks,omitempty"`
+	Views             int64    `json:"views"`
+	UniqueViewers     int64    `json:"unique_viewers"`
+	Installs          int64    `json:"installs"`
+	ClickThroughRate  *float64 `json:"click_through_rate,omitempty"`
+	ViewToInstallRate *float64 `json:"view_to_install_rate,omitempty"`
+}
+
+// Funnel breaks down discovery conversion by surface over a time range
+type Funnel struct {
+	ServerID string        `json:"server_id,omitempty"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Total    FunnelStage   `json:"total"`
+	Surfaces []FunnelStage `json:"surfaces"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// Install represents a user's installation of a server
+type Install struct {
+	ID                int64                  `json:"id"`
+	UserID            string                 `json:"user_id"`
+	ServerID          string                 `json:"server_id"`
+	Platform          string                 `json:"platform,omitempty"`
+	AppVersion        string                 `json:"app_version,omitempty"`
+	PackageType       string                 `json:"package_type,omitempty"`
+	Source            string                 `json:"source,omitempty"`   // discovery surface, e.g. search
+	Referrer          string                 `json:"referrer,omitempty"` // e.g. trending_list
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+	IPAddress         string                 `json:"-"`
+	UserAgent         string                 `json:"-"`
+	Flagged           bool                   `json:"flagged,omitempty"` // excluded from counts and scoring
+	InstalledAt       time.Time              `json:"installed_at"`
+	UninstalledAt     *time.Time             `json:"uninstalled_at,omitempty"`
+	UninstallReason   string                 `json:"uninstall_reason,omitempty"`
+	UninstallFeedback string                 `json:"uninstall_feedback,omitempty"`
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+)
+
+// Metric bucket intervals
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+)
+
+// MetricBucket holds the rolled-up activity of a server for one hour or day
+type MetricBucket struct {
+	ServerID     string              `json:"server_id" bson:"server_id"`
+	Interval     string              `json:"interval" bson:"interval"`
+	BucketStart  time.Time           `json:"bucket_start" bson:"bucket_start"`
+	Usage        UsageMetrics        `json:"usage" bson:"usage"`
+	Capabilities []CapabilityMetrics `json:"capabilities" bson:"capabilities"`
+	Installs     int64               `json:"installs" bson:"installs"`
+	Uninstalls   int64               `json:"uninstalls" bson:"uninstalls"`
+	UpdatedAt    time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// UsageMetrics summarizes usage events within a bucket
+type UsageMetrics struct {
+	Count        int64         `json:"count" bson:"count"`
+	SuccessCount int64         `json:"success_count" bson:"success_count"`
+	SuccessRate  float64       `json:"success_rate" bson:"success_rate"`
+	ToolCalls    int64         `json:"tool_calls" bson:"tool_calls"`
+	PromptUses   int64         `json:"prompt_uses" bson:"prompt_uses"`
+	TemplateUses int64         `json:"template_uses" bson:"template_uses"`
+	LatencyP50   float64       `json:"latency_p50_ms" bson:"latency_p50_ms"`
+	LatencyP95   float64       `json:"latency_p95_ms" bson:"latency_p95_ms"`
+	LatencyP99   float64       `json:"latency_p99_ms" bson:"latency_p99_ms"`
+	Latency      LatencySketch `json:"-" bson:"latency"`
+}
+
+// CapabilityMetrics summarizes usage of a single tool, prompt or template
+type CapabilityMetrics struct {
+	Type         string `json:"type" bson:"type"`
+	Name         string `json:"name" bson:"name"`
+	UsageMetrics `bson:",inline"`
+}
+
+// Record adds a single usage event to the metrics
+func (m *UsageMetrics) Record(event *UsageEvent) {
+	m.Count++
+	if event.Success {
+		m.SuccessCount++
+	}
+
+	switch event.Type {
+	case UsageToolCall:
+		m.ToolCalls++
+	case UsagePromptUse:
+		m.PromptUses++
+	case UsageTemplateUse:
+		m.TemplateUses++
+	}
+
+	m.Latency.Add(float64(event.DurationMs))
+}
+
+// Merge adds another set of metrics into this one
+func (m *UsageMetrics) Merge(other *UsageMetrics) {
+	m.Count += other.Count
+	m.SuccessCount += other.SuccessCount
+	m.ToolCalls += other.ToolCalls
+	m.PromptUses += other.PromptUses
+	m.TemplateUses += other.TemplateUses
+	m.Latency.Merge(other.Latency)
+}
+
+// Finalize derives the success rate and latency percentiles from the counts
+func (m *UsageMetrics) Finalize() {
+	m.SuccessRate = 0
+	if m.Count > 0 {
+		m.SuccessRate = float64(m.SuccessCount) / float64(m.Count)
+	}
+	m.LatencyP50 = m.Latency.Quantile(0.50)
+	m.LatencyP95 = m.Latency.Quantile(0.95)
+	m.LatencyP99 = m.Latency.Quantile(0.99)
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+)
+
+// QualityReport explains how a server's quality score was derived
+type QualityReport struct {
+	ServerID     string             `json:"server_id" bson:"server_id"`
+	Score        float64            `json:"score" bson:"score"`
+	Components   []QualityComponent `json:"components" bson:"components"`
+	Suggestions  []string           `json:"suggestions" bson:"suggestions"`
+	CalculatedAt time.Time          `json:"calculated_at" bson:"calculated_at"`
+}
+
+// QualityComponent is one weighted part of the quality score, scored 0-100
+type QualityComponent struct {
+	Name   string         `json:"name" bson:"name"`
+	Score  float64        `json:"score" bson:"score"`
+	Weight float64        `json:"weight" bson:"weight"`
+	NoData bool           `json:"no_data,omitempty" bson:"no_data,omitempty"`
+	Checks []QualityCheck `json:"checks" bson:"checks"`
+}
+
+// QualityCheck is a single signal within a component, scored 0-1. Checks
+// without data are left out of the component score.
+type QualityCheck struct {
+	Name       string  `json:"name" bson:"name"`
+	Score      float64 `json:"score" bson:"score"`
+	Weight     float64 `json:"weight" bson:"weight"`
+	Detail     string  `json:"detail" bson:"detail"`
+	Suggestion string  `json:"suggestion,omitempty" bson:"suggestion,omitempty"`
+	NoData     bool    `json:"no_data,omitempty" bson:"no_data,omitempty"`
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+)
+
+// Rankings that are snapshotted daily
+const (
+	RankingTrending   = "trending"
+	RankingPopularity = "popularity"
+)
+
+// RankSnapshot is a server's standing in the rankings on one day. Ranks
+// are 1-based; zero means the server was not ranked.
+type RankSnapshot struct {
+	ServerID        string         `json:"server_id" bson:"server_id"`
+	Day             time.Time      `json:"day" bson:"day"`
+	TrendingScore   float64        `json:"trending_score" bson:"trending_score"`
+	PopularityScore float64        `json:"popularity_score" bson:"popularity_score"`
+	TrendingRank    int            `json:"trending_rank,omitempty" bson:"trending_rank"`
+	PopularityRank  int            `json:"popularity_rank,omitempty" bson:"popularity_rank"`
+	GrowthRate      float64        `json:"growth_rate" bson:"growth_rate"`
+	Velocity        string         `json:"velocity,omitempty" bson:"velocity"`
+	Categories      []CategoryRank `json:"categories,omitempty" bson:"categories"`
+	UpdatedAt       time.Time      `json:"updated_at" bson:"updated_at"`
+}
+
+// CategoryRank is a server's standing within one category on a day
+type CategoryRank struct {
+	Category       string `json:"category" bson:"category"`
+	TrendingRank   int    `json:"trending_rank,omitempty" bson:"trending_rank"`
+	PopularityRank int    `json:"popularity_rank,omitempty" bson:"popularity_rank"`
+}
+
+// RankMover is a server whose rank changed over a period
+type RankMover struct {
+	ServerID     string        `json:"server_id"`
+	Rank         int           `json:"rank"`
+	PreviousRank int           `json:"previous_rank,omitempty"` // zero when newly ranked
+	Change       int           `json:"change"`                  // places climbed; negative for falls
+	Velocity     string        `json:"velocity"`
+	Server       *ServerDetail `json:"server,omitempty"`
+}
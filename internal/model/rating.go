@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+)
+
+// Review represents a user's star rating of a server with an optional written review
+type Review struct {
+	ID              int64     `json:"id"`
+	UserID          string    `json:"user_id"`
+	ServerID        string    `json:"server_id"`
+	Rating          int       `json:"rating"`
+	Title           string    `json:"title,omitempty"`
+	Content         string    `json:"content,omitempty"`
+	Pros            []string  `json:"pros,omitempty"`
+	Cons            []string  `json:"cons,omitempty"`
+	WouldRecommend  *bool     `json:"would_recommend,omitempty"`
+	VerifiedInstall bool      `json:"verified_install"`
+	HelpfulCount    int       `json:"helpful_count"`
+	UnhelpfulCount  int       `json:"unhelpful_count"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Moderation state (only approved reviews are public and counted)
+	Status            string     `json:"status"`
+	ModerationReasons []string   `json:"moderation_reasons,omitempty"`
+	FlagCount         int        `json:"flag_count,omitempty"`
+	ModeratedBy       string     `json:"moderated_by,omitempty"`
+	ModeratedAt       *time.Time `json:"moderated_at,omitempty"`
+}
+
+// ReviewFlag represents a user's report of a review
+type ReviewFlag struct {
+	RatingID  int64     `json:"rating_id"`
+	UserID    string    `json:"user_id"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RatingSummary aggregates a server's ratings
+type RatingSummary struct {
+	Average      float64       `json:"average"`
+	Weighted     float64       `json:"weighted_average"`
+	Count        int64         `json:"count"`
+	Total        int64         `json:"total"`
+	Distribution map[int]int64 `json:"distribution"`
+}
+
+// RatingUpdate announces a change in a server's rating
+type RatingUpdate struct {
+	ServerID       string  `json:"server_id"`
+	RatingAverage  float64 `json:"rating_average"`
+	RatingCount    int64   `json:"rating_count"`
+	RatingWeighted float64 `json:"rating_weighted"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// CoInstalls lists the servers most often installed alongside a server
+type CoInstalls struct {
+	ServerID  string          `json:"server_id" bson:"server_id"`
+	Installs  int64           `json:"installs" bson:"installs"` // users with an active install
+	Related   []RelatedServer `json:"related" bson:"related"`
+	UpdatedAt time.Time       `json:"updated_at" bson:"updated_at"`
+}
+
+// RelatedServer is a server installed by the same users as another.
+// Jaccard is the share of either server's users who have both; lift is how
+// much more often they co-occur than if installs were independent.
+type RelatedServer struct {
+	ServerID   string        `json:"server_id" bson:"server_id"`
+	CoInstalls int64         `json:"co_installs" bson:"co_installs"`
+	Jaccard    float64       `json:"jaccard" bson:"jaccard"`
+	Lift       float64       `json:"lift" bson:"lift"`
+	Server     *ServerDetail `json:"server,omitempty" bson:"-"`
+}
+
+// Recommendation is a server suggested from a user's current installs
+type Recommendation struct {
+	ServerID string        `json:"server_id"`
+	Score    float64       `json:"score"`
+	Because  []string      `json:"because"` // installed servers that contributed most
+	Server   *ServerDetail `json:"server,omitempty"`
+}
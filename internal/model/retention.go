@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// RetentionRates holds the share of installs still present N days after
+// installing. A rate is nil when no install is old enough to measure it.
+type RetentionRates struct {
+	Day1  *float64 `json:"day_1"`
+	Day7  *float64 `json:"day_7"`
+	Day30 *float64 `json:"day_30"`
+}
+
+// RetentionCohort holds the retention of the installs made in one week
+type RetentionCohort struct {
+	Week      time.Time      `json:"week"`
+	Installs  int64          `json:"installs"`
+	Retention RetentionRates `json:"retention"`
+}
+
+// UninstallReason counts uninstalls that gave the same reason
+type UninstallReason struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+)
+
+// Search attribution event types
+const (
+	SearchEventClick   = "click"
+	SearchEventInstall = "install"
+)
+
+// SearchLog records a single search and the results it returned
+type SearchLog struct {
+	ID           string                 `json:"search_id" bson:"_id"`
+	UserID       string                 `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Query        string                 `json:"query" bson:"query"`
+	Normalized   string                 `json:"normalized" bson:"normalized"`
+	Filters      map[string]interface{} `json:"filters,omitempty" bson:"filters,omitempty"`
+	Sort         string                 `json:"sort" bson:"sort"`
+	Offset       int                    `json:"offset" bson:"offset"`
+	Limit        int                    `json:"limit" bson:"limit"`
+	ResultCount  int                    `json:"result_count" bson:"result_count"`
+	ResultIDs    []string               `json:"result_ids" bson:"result_ids"`
+	Personalized bool                   `json:"personalized,omitempty" bson:"personalized,omitempty"`
+	ExperimentID int64                  `json:"experiment_id,omitempty" bson:"experiment_id,omitempty"`
+	Variant      string                 `json:"variant,omitempty" bson:"variant,omitempty"`
+	LatencyMs    int64                  `json:"latency_ms" bson:"latency_ms"`
+	CreatedAt    time.Time              `json:"created_at" bson:"created_at"`
+}
+
+// SearchEvent attributes a click or install to a search result
+type SearchEvent struct {
+	SearchID  string    `json:"search_id" bson:"search_id"`
+	ServerID  string    `json:"server_id" bson:"server_id"`
+	Type      string    `json:"type" bson:"type"`
+	Position  int       `json:"position" bson:"position"`
+	UserID    string    `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// QueryReport summarizes the searches for one normalized query
+type QueryReport struct {
+	Query        string  `json:"query" bson:"_id"`
+	Searches     int64   `json:"searches" bson:"searches"`
+	ZeroResults  int64   `json:"zero_results" bson:"zero_results"`
+	AvgResults   float64 `json:"avg_results" bson:"avg_results"`
+	AvgLatencyMs float64 `json:"avg_latency_ms" bson:"avg_latency_ms"`
+}
+
+// PositionCTR holds the click-through and install rates of one result position
+type PositionCTR struct {
+	Position    int     `json:"position"`
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	Installs    int64   `json:"installs"`
+	CTR         float64 `json:"ctr"`
+	InstallRate float64 `json:"install_rate"`
+}
@@ -29,8 +29,13 @@ type ServerDetail struct {
 	IndexedAt       time.Time          `json:"indexed_at"`
 	LastUpdated     time.Time          `json:"last_updated"`
 	InstallCount    int64              `json:"install_count"`
+	ActiveInstallCount int64           `json:"active_install_count"`
 	RatingAverage   float64            `json:"rating_average"`
 	RatingCount     int64              `json:"rating_count"`
+	RatingWeighted  float64            `json:"rating_weighted"`
+	ToolCallCount   int64              `json:"tool_call_count"`
+	PromptUseCount  int64              `json:"prompt_use_count"`
+	TemplateUseCount int64             `json:"template_use_count"`
 	PopularityScore float64            `json:"popularity_score"`
 	TrendingScore   float64            `json:"trending_score"`
 	QualityScore    float64            `json:"quality_score"`
@@ -77,6 +82,7 @@ type ServerStats struct {
 	RatingTotal      float64   `json:"rating_total"`
 	RatingCount      int64     `json:"rating_count"`
 	RatingAverage    float64   `json:"rating_average"`
+	RatingWeighted   float64   `json:"rating_weighted"`
 	ToolCallCount    int64     `json:"tool_call_count"`
 	PromptUseCount   int64     `json:"prompt_use_count"`
 	TemplateUseCount int64     `json:"template_use_count"`
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// sketchAccuracy is the relative error guaranteed for sketch quantiles
+const sketchAccuracy = 0.01
+
+var (
+	sketchGamma    = (1 + sketchAccuracy) / (1 - sketchAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+// LatencySketch is a mergeable quantile sketch over positive values. Values
+// are counted in logarithmic bins, so quantiles are accurate to within 1% and
+// two sketches merge by adding their bin counts.
+type LatencySketch struct {
+	Zero int64       `json:"-" bson:"zero"`
+	Bins []SketchBin `json:"-" bson:"bins"`
+}
+
+// SketchBin counts the values that fall into one logarithmic bin
+type SketchBin struct {
+	Index int32 `bson:"i"`
+	Count int64 `bson:"c"`
+}
+
+// Add records a single value
+func (s *LatencySketch) Add(value float64) {
+	s.addCount(value, 1)
+}
+
+// Merge adds the counts of another sketch into this one
+func (s *LatencySketch) Merge(other LatencySketch) {
+	s.Zero += other.Zero
+	for _, bin := range other.Bins {
+		s.addBin(bin.Index, bin.Count)
+	}
+}
+
+// Count returns the number of recorded values
+func (s *LatencySketch) Count() int64 {
+	total := s.Zero
+	for _, bin := range s.Bins {
+		total += bin.Count
+	}
+	return total
+}
+
+// Quantile returns the estimated value at quantile q (0 <= q <= 1)
+func (s *LatencySketch) Quantile(q float64) float64 {
+	total := s.Count()
+	if total == 0 {
+		return 0
+	}
+
+	// Rank of the requested value, zero-based
+	rank := int64(math.Ceil(q*float64(total))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	seen := s.Zero
+	if rank < seen {
+		return 0
+	}
+	for _, bin := range s.Bins {
+		seen += bin.Count
+		if rank < seen {
+			return 2 * math.Pow(sketchGamma, float64(bin.Index)) / (sketchGamma + 1)
+		}
+	}
+
+	last := s.Bins[len(s.Bins)-1]
+	return 2 * math.Pow(sketchGamma, float64(last.Index)) / (sketchGamma + 1)
+}
+
+// addCount records count occurrences of value
+func (s *LatencySketch) addCount(value float64, count int64) {
+	if value <= 0 || math.IsNaN(value) {
+		s.Zero += count
+		return
+	}
+	s.addBin(int32(math.Ceil(math.Log(value)/sketchLogGamma)), count)
+}
+
+// addBin adds to a bin, keeping bins ordered by index
+func (s *LatencySketch) addBin(index int32, count int64) {
+	i := sort.Search(len(s.Bins), func(i int) bool { return s.Bins[i].Index >= index })
+	if i < len(s.Bins) && s.Bins[i].Index == index {
+		s.Bins[i].Count += count
+		return
+	}
+
+	s.Bins = append(s.Bins, SketchBin{})
+	copy(s.Bins[i+1:], s.Bins[i:])
+	s.Bins[i] = SketchBin{Index: index, Count: count}
+}
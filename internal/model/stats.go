@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+)
+
+// GlobalStats summarizes the whole platform
+type GlobalStats struct {
+	TotalServers     int64            `json:"total_servers"`
+	TotalInstalls    int64            `json:"total_installs"`
+	ActiveInstalls   int64            `json:"active_installs"`
+	ActiveUsers      int64            `json:"active_users"`
+	DailyActiveUsers int64            `json:"daily_active_users"`
+	TotalReviews     int64            `json:"total_reviews"`
+	ByCategory       map[string]int64 `json:"by_category"`
+	ByPackageType    map[string]int64 `json:"by_package_type"`
+	ByTransport      map[string]int64 `json:"by_transport"`
+	BySource         map[string]int64 `json:"by_source"`
+	Growth           GrowthStats      `json:"growth"`
+	GeneratedAt      time.Time        `json:"generated_at"`
+}
+
+// GrowthStats counts platform activity over the last 30 days
+type GrowthStats struct {
+	Servers     int64 `json:"servers_30d"`
+	Installs    int64 `json:"installs_30d"`
+	Users       int64 `json:"users_30d"`
+	UsageEvents int64 `json:"usage_events_30d"`
+}
+
+// CategoryStats summarizes the servers in one category
+type CategoryStats struct {
+	Category       string        `json:"category"`
+	Servers        int64         `json:"servers"`
+	NewServers     int64         `json:"new_servers_30d"`
+	TotalInstalls  int64         `json:"total_installs"`
+	ActiveInstalls int64         `json:"active_installs"`
+	AverageRating  float64       `json:"average_rating"`
+	TopServers     []StatsServer `json:"top_servers"`
+}
+
+// StatsServer identifies a server listed in statistics
+type StatsServer struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	TrendingScore float64 `json:"trending_score"`
+}
+
+// ToolStats ranks tools across the platform
+type ToolStats struct {
+	Days         int               `json:"days"`
+	MostUsed     []ToolUsage       `json:"most_used"`
+	MostDeclared []ToolDeclaration `json:"most_declared"`
+	GeneratedAt  time.Time         `json:"generated_at"`
+}
+
+// ToolUsage counts calls to one server's tool
+type ToolUsage struct {
+	ServerID    string  `json:"server_id" bson:"server_id"`
+	Name        string  `json:"name" bson:"name"`
+	Calls       int64   `json:"calls" bson:"calls"`
+	SuccessRate float64 `json:"success_rate" bson:"-"`
+	Successes   int64   `json:"-" bson:"successes"`
+}
+
+// ToolDeclaration counts the servers declaring a tool name
+type ToolDeclaration struct {
+	Name    string `json:"name"`
+	Servers int64  `json:"servers"`
+}
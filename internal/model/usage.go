@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Usage event types
+const (
+	UsageToolCall    = "tool_call"
+	UsagePromptUse   = "prompt_use"
+	UsageTemplateUse = "template_use"
+)
+
+// UsageEvent represents a single use of a server capability
+type UsageEvent struct {
+	ID         primitive.ObjectID     `json:"-" bson:"_id,omitempty"`
+	ServerID   string                 `json:"server_id" bson:"server_id"`
+	UserID     string                 `json:"user_id" bson:"user_id"`
+	Type       string                 `json:"type" bson:"type"`
+	Name       string                 `json:"name" bson:"name"`
+	Timestamp  time.Time              `json:"timestamp" bson:"timestamp"`
+	DurationMs int64                  `json:"duration_ms" bson:"duration_ms"`
+	Success    bool                   `json:"success" bson:"success"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	ReceivedAt time.Time              `json:"received_at" bson:"received_at"`
+}
+
+// Capabilities returns the server's declared capabilities of a usage event type
+func (s *ServerDetail) Capabilities(eventType string) []Capability {
+	switch eventType {
+	case UsageToolCall:
+		return s.Tools
+	case UsagePromptUse:
+		return s.Prompts
+	case UsageTemplateUse:
+		return s.Templates
+	default:
+		return nil
+	}
+}
+
+// HasCapability reports whether the server declares a capability of the given
+// usage event type and name
+func (s *ServerDetail) HasCapability(eventType, name string) bool {
+	for _, capability := range s.Capabilities(eventType) {
+		if capability.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys used to relay events between instances
+const (
+	broadcastChannel = "realtime:events"
+	claimPrefix      = "realtime:claim:"
+)
+
+// Relay settings
+const (
+	broadcastTimeout = 2 * time.Second
+	// seenWindow is how long relayed message IDs are remembered to drop
+	// redelivered messages
+	seenWindow = 5 * time.Minute
+	maxSeen    = 10000
+)
+
+// message is an event relayed between instances
+type message struct {
+	ID       string `json:"id"`
+	Instance string `json:"instance"`
+	Event    *Event `json:"event"`
+}
+
+// Broadcaster relays events between instances over Redis pub/sub, so clients
+// connected to any instance receive events published on every instance.
+// Events are delivered to local subscribers directly and relayed to the
+// others; each instance ignores its own messages and ones it has already
+// seen. Pub/sub does not store messages, so events published while an
+// instance is disconnected from Redis do not reach it.
+type Broadcaster struct {
+	redis    *redis.Client
+	hub      *Hub
+	instance string
+	pubsub   *redis.PubSub
+	seq      atomic.Uint64
+	seen     *recentSet
+	stopped  chan struct{}
+}
+
+// NewBroadcaster subscribes to events from other instances and attaches the
+// broadcaster to hub. It must be created before events are published. An
+// empty instanceID is replaced by the hostname and a random suffix.
+func NewBroadcaster(client *redis.Client, hub *Hub, instanceID string) (*Broadcaster, error) {
+	if instanceID == "" {
+		instanceID = defaultInstanceID()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pubsub := client.Subscribe(ctx, broadcastChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to realtime events: %w", err)
+	}
+
+	b := &Broadcaster{
+		redis:    client,
+		hub:      hub,
+		instance: instanceID,
+		pubsub:   pubsub,
+		seen:     newRecentSet(maxSeen),
+		stopped:  make(chan struct{}),
+	}
+	hub.relay = b
+
+	go b.run()
+
+	log.Printf("Relaying realtime events as instance %s", instanceID)
+
+	return b, nil
+}
+
+// Instance returns the ID this instance relays events under
+func (b *Broadcaster) Instance() string {
+	return b.instance
+}
+
+// Close stops receiving events from other instances
+func (b *Broadcaster) Close() error {
+	err := b.pubsub.Close()
+	<-b.stopped
+	return err
+}
+
+// send relays an event to the other instances
+func (b *Broadcaster) send(event *Event) {
+	payload, err := json.Marshal(message{
+		ID:       fmt.Sprintf("%s-%d", b.instance, b.seq.Add(1)),
+		Instance: b.instance,
+		Event:    event,
+	})
+	if err != nil {
+		log.Printf("Realtime broadcast error: failed to encode %s event: %v", event.Type, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), broadcastTimeout)
+	defer cancel()
+
+	if err := b.redis.Publish(ctx, broadcastChannel, payload).Err(); err != nil {
+		log.Printf("Realtime broadcast error: %v", err)
+	}
+}
+
+// claim reserves a deduplication key across instances for window. If Redis
+// is unavailable the key is claimed locally, so events are not lost.
+func (b *Broadcaster) claim(key string, window time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), broadcastTimeout)
+	defer cancel()
+
+	claimed, err := b.redis.SetNX(ctx, claimPrefix+key, b.instance, window).Result()
+	if err != nil {
+		log.Printf("Realtime claim error: %v", err)
+		return b.hub.claims.add(key, window)
+	}
+
+	return claimed
+}
+
+// run delivers events relayed by other instances until the subscription closes
+func (b *Broadcaster) run() {
+	defer close(b.stopped)
+
+	for msg := range b.pubsub.Channel() {
+		var m message
+		if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+			log.Printf("Realtime relay error: failed to decode message: %v", err)
+			continue
+		}
+		if m.Event == nil || m.Instance == b.instance {
+			continue
+		}
+		if !b.seen.add(m.ID, seenWindow) {
+			continue
+		}
+
+		// Renumbered on delivery, like local events, keeping its origin
+		m.Event.ID = 0
+		b.hub.deliver(m.Event)
+	}
+}
+
+// defaultInstanceID identifies this process by hostname and a random suffix
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "analytics"
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return host
+	}
+
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// recentSet remembers keys for a while, holding at most max keys
+type recentSet struct {
+	mu      sync.Mutex
+	max     int
+	expires map[string]time.Time
+}
+
+// newRecentSet creates an empty set
+func newRecentSet(max int) *recentSet {
+	return &recentSet{
+		max:     max,
+		expires: make(map[string]time.Time),
+	}
+}
+
+// add remembers key for ttl and reports whether it was not already remembered
+func (r *recentSet) add(key string, ttl time.Duration) bool {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if expires, ok := r.expires[key]; ok && now.Before(expires) {
+		return false
+	}
+
+	if len(r.expires) >= r.max {
+		r.prune(now)
+	}
+	r.expires[key] = now.Add(ttl)
+
+	return true
+}
+
+// prune forgets expired keys, and arbitrary ones if that frees too little
+// room, so pruning stays rare
+func (r *recentSet) prune(now time.Time) {
+	for key, expires := range r.expires {
+		if !now.Before(expires) {
+			delete(r.expires, key)
+		}
+	}
+
+	for key := range r.expires {
+		if len(r.expires) < r.max/2 {
+			break
+		}
+		delete(r.expires, key)
+	}
+}
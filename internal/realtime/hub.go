@@ -0,0 +1,437 @@
+package realtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Channels clients can subscribe to
+const (
+	ChannelTrending   = "trending"
+	ChannelNewServers = "new_servers"
+	ChannelRatings    = "ratings"
+)
+
+// Channels lists the channels clients can subscribe to
+var Channels = []string{ChannelTrending, ChannelNewServers, ChannelRatings}
+
+// Event types
+const (
+	TypeTrendingUpdate = "trending_update"
+	TypeNewServer      = "new_server"
+	TypeServerUpdated  = "server_updated"
+	TypeServerDeleted  = "server_deleted"
+	TypeRatingUpdate   = "rating_update"
+)
+
+// Subscription limits
+const (
+	maxServerSubscriptions = 100
+	maxServerIDLength      = 256
+	// MaxOriginLength bounds the event origins clients resume from
+	MaxOriginLength = 256
+	// maxClaims bounds the deduplication keys remembered without a broadcaster
+	maxClaims = 10000
+)
+
+// Reasons a subscriber is closed by the hub
+var (
+	ErrSlowSubscriber     = errors.New("subscriber fell behind")
+	ErrHubClosed          = errors.New("realtime hub closed")
+	ErrTooManySubscribers = errors.New("too many realtime subscribers")
+)
+
+// Event is a realtime update. Events about a server carry its ID and also
+// reach the subscribers of that server. IDs increase in delivery order on
+// each instance; Origin names the event on every instance as the publishing
+// instance and its ID there.
+type Event struct {
+	ID        uint64          `json:"id"`
+	Origin    string          `json:"origin"`
+	Type      string          `json:"type"`
+	Channel   string          `json:"channel,omitempty"`
+	ServerID  string          `json:"server_id,omitempty"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp time.Time       `json:"timestamp"`
+
+	encoded []byte
+}
+
+// JSON returns the event encoded once for every subscriber
+func (e *Event) JSON() []byte {
+	return e.encoded
+}
+
+// Hub fans published events out to subscribers of their channel or server.
+// Each subscriber has a bounded queue; a subscriber whose queue is full is
+// dropped rather than holding up publishers. The latest events are kept so
+// reconnecting subscribers can catch up.
+type Hub struct {
+	maxSubscribers int
+	buffer         int
+
+	// relay forwards published events to other instances when set
+	relay  *Broadcaster
+	claims *recentSet
+
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+
+	// deliverMu orders deliveries, so event IDs and the replay buffer
+	// match the order subscribers see
+	deliverMu sync.Mutex
+	seq       uint64
+	replay    []*Event
+	next      int
+	count     int
+}
+
+// NewHub creates a hub holding at most maxSubscribers subscribers, each
+// queueing up to buffer events, and replaying up to replaySize events
+func NewHub(maxSubscribers, buffer, replaySize int) *Hub {
+	if buffer < 1 {
+		buffer = 1
+	}
+	if replaySize < 1 {
+		replaySize = 1
+	}
+
+	return &Hub{
+		maxSubscribers: maxSubscribers,
+		buffer:         buffer,
+		claims:         newRecentSet(maxClaims),
+		subscribers:    make(map[*Subscriber]struct{}),
+		// IDs start from the clock so they keep increasing across restarts
+		seq:    uint64(time.Now().UnixMicro()),
+		replay: make([]*Event, replaySize),
+	}
+}
+
+// Publish sends an event to the subscribers of its channel and server. Either
+// may be empty. A nil hub discards events, so publishers need not check
+// whether realtime updates are enabled.
+func (h *Hub) Publish(channel, eventType, serverID string, data interface{}) {
+	if h == nil {
+		return
+	}
+
+	event, err := newEvent(channel, eventType, serverID, data)
+	if err != nil {
+		log.Printf("Realtime publish error: %v", err)
+		return
+	}
+
+	h.deliver(event)
+	if h.relay != nil {
+		h.relay.send(event)
+	}
+}
+
+// PublishOnce publishes an event unless one with the same key was published
+// within window, by this or, with a broadcaster, any other instance. It is
+// for events that every instance may produce, such as scoring job results.
+func (h *Hub) PublishOnce(key string, window time.Duration, channel, eventType, serverID string, data interface{}) {
+	if h == nil {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	key = hex.EncodeToString(sum[:])
+
+	var claimed bool
+	if h.relay != nil {
+		claimed = h.relay.claim(key, window)
+	} else {
+		claimed = h.claims.add(key, window)
+	}
+	if !claimed {
+		return
+	}
+
+	h.Publish(channel, eventType, serverID, data)
+}
+
+// Subscribe registers a subscriber with no subscriptions
+func (h *Hub) Subscribe() (*Subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	if h.maxSubscribers > 0 && len(h.subscribers) >= h.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	s := &Subscriber{
+		hub:      h,
+		events:   make(chan *Event, h.buffer),
+		done:     make(chan struct{}),
+		channels: make(map[string]bool),
+		servers:  make(map[string]bool),
+	}
+	h.subscribers[s] = struct{}{}
+
+	return s, nil
+}
+
+// Replay returns the buffered events after the one with the given origin
+// that the subscriber wants, oldest first. Origins are shared by every
+// instance, so clients can resume on any of them. When the origin is no
+// longer buffered it returns every buffered event the subscriber wants and
+// reports false, since some may be missing. Events delivered meanwhile can
+// also be queued for the subscriber; callers skip those with IDs they have
+// already sent.
+func (h *Hub) Replay(s *Subscriber, after string) ([]*Event, bool) {
+	h.deliverMu.Lock()
+	defer h.deliverMu.Unlock()
+
+	buffered := make([]*Event, h.count)
+	start := 0
+	complete := false
+	for i := range buffered {
+		buffered[i] = h.replay[(h.next-h.count+i+len(h.replay))%len(h.replay)]
+		if buffered[i].Origin == after {
+			start, complete = i+1, true
+		}
+	}
+
+	var events []*Event
+	for _, event := range buffered[start:] {
+		if s.matches(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, complete
+}
+
+// Subscribers returns the number of connected subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// Close ends every subscription and rejects new ones
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.closed = true
+	subscribers := make([]*Subscriber, 0, len(h.subscribers))
+	for s := range h.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range subscribers {
+		s.end(ErrHubClosed)
+	}
+}
+
+// deliver numbers and buffers an event, queues it for every matching
+// subscriber and drops the ones that have fallen behind
+func (h *Hub) deliver(event *Event) {
+	h.deliverMu.Lock()
+	defer h.deliverMu.Unlock()
+
+	h.seq++
+	event.ID = h.seq
+	if event.Origin == "" {
+		event.Origin = strconv.FormatUint(h.seq, 10)
+		if h.relay != nil {
+			event.Origin = h.relay.instance + ":" + event.Origin
+		}
+	}
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Realtime publish error: failed to encode %s event: %v", event.Type, err)
+		return
+	}
+	event.encoded = encoded
+
+	h.replay[h.next] = event
+	h.next = (h.next + 1) % len(h.replay)
+	if h.count < len(h.replay) {
+		h.count++
+	}
+
+	var slow []*Subscriber
+
+	h.mu.RLock()
+	for s := range h.subscribers {
+		if !s.matches(event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		s.end(ErrSlowSubscriber)
+	}
+}
+
+// remove unregisters a subscriber
+func (h *Hub) remove(s *Subscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, s)
+	h.mu.Unlock()
+}
+
+// newEvent builds an event. It is numbered and encoded on delivery.
+func newEvent(channel, eventType, serverID string, data interface{}) (*Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	return &Event{
+		Type:      eventType,
+		Channel:   channel,
+		ServerID:  serverID,
+		Data:      payload,
+		Timestamp: time.Now().UTC(),
+	}, nil
+}
+
+// Subscriber receives the events of the channels and servers it subscribes to
+type Subscriber struct {
+	hub    *Hub
+	events chan *Event
+	done   chan struct{}
+	once   sync.Once
+	err    error
+
+	mu       sync.RWMutex
+	channels map[string]bool
+	servers  map[string]bool
+}
+
+// Events returns the subscriber's queued events
+func (s *Subscriber) Events() <-chan *Event {
+	return s.events
+}
+
+// Done is closed when the subscription ends
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the hub ended the subscription once Done is closed, or nil
+// if the subscriber closed it
+func (s *Subscriber) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the subscription
+func (s *Subscriber) Close() {
+	s.end(nil)
+}
+
+// Subscribe adds channels and servers to the subscription
+func (s *Subscriber) Subscribe(channels, servers []string) error {
+	for _, channel := range channels {
+		if !slices.Contains(Channels, channel) {
+			return fmt.Errorf("unknown channel: %s", channel)
+		}
+	}
+	for _, serverID := range servers {
+		if serverID == "" || len(serverID) > maxServerIDLength {
+			return fmt.Errorf("invalid server ID: %q", serverID)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := 0
+	for _, serverID := range servers {
+		if !s.servers[serverID] {
+			added++
+		}
+	}
+	if len(s.servers)+added > maxServerSubscriptions {
+		return fmt.Errorf("at most %d servers can be subscribed to", maxServerSubscriptions)
+	}
+
+	for _, channel := range channels {
+		s.channels[channel] = true
+	}
+	for _, serverID := range servers {
+		s.servers[serverID] = true
+	}
+
+	return nil
+}
+
+// Unsubscribe removes channels and servers from the subscription
+func (s *Subscriber) Unsubscribe(channels, servers []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, channel := range channels {
+		delete(s.channels, channel)
+	}
+	for _, serverID := range servers {
+		delete(s.servers, serverID)
+	}
+}
+
+// Subscriptions returns the subscribed channels and servers
+func (s *Subscriber) Subscriptions() (channels, servers []string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channels = make([]string, 0, len(s.channels))
+	for channel := range s.channels {
+		channels = append(channels, channel)
+	}
+	servers = make([]string, 0, len(s.servers))
+	for serverID := range s.servers {
+		servers = append(servers, serverID)
+	}
+	sort.Strings(channels)
+	sort.Strings(servers)
+
+	return channels, servers
+}
+
+// matches reports whether the subscriber wants an event
+func (s *Subscriber) matches(event *Event) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return (event.Channel != "" && s.channels[event.Channel]) ||
+		(event.ServerID != "" && s.servers[event.ServerID])
+}
+
+// end closes the subscription once, recording why
+func (s *Subscriber) end(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+		s.hub.remove(s)
+	})
+}
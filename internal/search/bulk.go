@@ -0,0 +1,151 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// scanPageSize is the number of servers fetched per page when scanning the index
+const scanPageSize = 500
+
+// ForEachServer calls fn for every indexed server in ID order. Only the given
+// source fields are loaded; pass nil to load full documents.
+func (s *Service) ForEachServer(ctx context.Context, fields []string, fn func(*model.ServerDetail) error) error {
+	var searchAfter []interface{}
+
+	for {
+		esQuery := map[string]interface{}{
+			"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+			"sort":  []interface{}{map[string]interface{}{"id": "asc"}},
+			"size":  scanPageSize,
+		}
+		if fields != nil {
+			esQuery["_source"] = append([]string{"id"}, fields...)
+		}
+		if searchAfter != nil {
+			esQuery["search_after"] = searchAfter
+		}
+
+		body, err := json.Marshal(esQuery)
+		if err != nil {
+			return fmt.Errorf("failed to marshal query: %w", err)
+		}
+
+		res, err := s.client.Search(
+			s.client.Search.WithContext(ctx),
+			s.client.Search.WithIndex(serverIndexName),
+			s.client.Search.WithBody(bytes.NewReader(body)),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan servers: %w", err)
+		}
+
+		var esResult struct {
+			Hits struct {
+				Hits []struct {
+					Source model.ServerDetail `json:"_source"`
+					Sort   []interface{}      `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+
+		if res.IsError() {
+			res.Body.Close()
+			return fmt.Errorf("scan error: %s", res.String())
+		}
+		err = json.NewDecoder(res.Body).Decode(&esResult)
+		res.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		hits := esResult.Hits.Hits
+		for i := range hits {
+			if err := fn(&hits[i].Source); err != nil {
+				return err
+			}
+		}
+
+		if len(hits) < scanPageSize {
+			return nil
+		}
+		searchAfter = hits[len(hits)-1].Sort
+	}
+}
+
+// BulkUpdateFields applies partial updates to many servers in one request.
+// Servers that are no longer indexed are skipped.
+func (s *Service) BulkUpdateFields(ctx context.Context, updates map[string]map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	// Build NDJSON body of update actions
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for id, fields := range updates {
+		action := map[string]interface{}{
+			"update": map[string]interface{}{"_index": serverIndexName, "_id": id},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := encoder.Encode(map[string]interface{}{"doc": fields}); err != nil {
+			return fmt.Errorf("failed to marshal bulk update: %w", err)
+		}
+	}
+
+	res, err := s.client.Bulk(
+		bytes.NewReader(buf.Bytes()),
+		s.client.Bulk.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute bulk update: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("bulk update error: %s", res.String())
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	// Report the first real failure, ignoring deleted servers
+	var failed int
+	var firstErr string
+	for _, item := range result.Items {
+		for _, status := range item {
+			if status.Status >= 300 && status.Status != 404 {
+				if failed == 0 {
+					firstErr = fmt.Sprintf("%s: %s", status.ID, status.Error.Reason)
+				}
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d bulk updates failed, first: %s", failed, firstErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Recent activity types
+const (
+	ActivityNew     = "new"
+	ActivityUpdated = "updated"
+)
+
+// DiscoveryQuery represents parameters for the discovery lists
+type DiscoveryQuery struct {
+	Category     string               `json:"category,omitempty"`
+	Limit        int                  `json:"limit"`
+	MinReviews   int                  `json:"min_reviews,omitempty"`
+	ActivityType string               `json:"activity_type,omitempty"`
+	Ranking      *model.RankingParams `json:"ranking,omitempty"`
+}
+
+// Trending returns servers ordered by trending score
+func (s *Service) Trending(ctx context.Context, query DiscoveryQuery) ([]model.ServerDetail, error) {
+	return s.listServers(ctx, s.discoveryFilters(query), listSort(query, []interface{}{
+		map[string]interface{}{"trending_score": map[string]interface{}{"order": "desc"}},
+		map[string]interface{}{"popularity_score": map[string]interface{}{"order": "desc"}},
+		map[string]interface{}{"install_count": map[string]interface{}{"order": "desc"}},
+	}), query.Limit)
+}
+
+// TopRated returns servers ordered by weighted rating with at least MinReviews ratings
+func (s *Service) TopRated(ctx context.Context, query DiscoveryQuery) ([]model.ServerDetail, error) {
+	filters := s.discoveryFilters(query)
+	filters = append(filters, map[string]interface{}{
+		"range": map[string]interface{}{
+			"rating_count": map[string]interface{}{"gte": query.MinReviews},
+		},
+	})
+
+	return s.listServers(ctx, filters, listSort(query, []interface{}{
+		map[string]interface{}{"rating_weighted": map[string]interface{}{"order": "desc"}},
+		map[string]interface{}{"rating_count": map[string]interface{}{"order": "desc"}},
+	}), query.Limit)
+}
+
+// Recent returns newly indexed or recently updated servers
+func (s *Service) Recent(ctx context.Context, query DiscoveryQuery) ([]model.ServerDetail, error) {
+	field := "last_updated"
+	if query.ActivityType == ActivityNew {
+		field = "indexed_at"
+	}
+
+	return s.listServers(ctx, s.discoveryFilters(query), listSort(query, []interface{}{
+		map[string]interface{}{field: map[string]interface{}{"order": "desc"}},
+	}), query.Limit)
+}
+
+// listSort returns the order an experiment chose for a list, or the list's
+// own order
+func listSort(query DiscoveryQuery, defaults []interface{}) []interface{} {
+	if query.Ranking != nil {
+		if clauses := sortClauses(query.Ranking.Sort); clauses != nil {
+			return clauses
+		}
+	}
+	return defaults
+}
+
+// discoveryFilters builds the filters shared by all discovery lists
+func (s *Service) discoveryFilters(query DiscoveryQuery) []interface{} {
+	filters := []interface{}{}
+
+	if query.Category != "" {
+		filters = append(filters, map[string]interface{}{
+			"term": map[string]interface{}{
+				"categories": query.Category,
+			},
+		})
+	}
+
+	return filters
+}
+
+// listServers runs a filtered, sorted query and returns the matching servers
+func (s *Service) listServers(ctx context.Context, filters []interface{}, sort []interface{}, limit int) ([]model.ServerDetail, error) {
+	esQuery := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": filters,
+			},
+		},
+		"sort": sort,
+		"size": limit,
+	}
+
+	body, err := json.Marshal(esQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	res, err := s.client.Search(
+		s.client.Search.WithContext(ctx),
+		s.client.Search.WithIndex(serverIndexName),
+		s.client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute search: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("search error: %s", res.String())
+	}
+
+	var esResult struct {
+		Hits struct {
+			Hits []struct {
+				Source model.ServerDetail `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&esResult); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	servers := make([]model.ServerDetail, len(esResult.Hits.Hits))
+	for i, hit := range esResult.Hits.Hits {
+		servers[i] = hit.Source
+	}
+
+	return servers, nil
+}
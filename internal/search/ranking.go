@@ -0,0 +1,146 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Sort orders
+const (
+	SortRelevance  = "relevance"
+	SortPopularity = "popularity"
+	SortTrending   = "trending"
+	SortRating     = "rating"
+	SortRecent     = "recent"
+)
+
+// defaultSearchFields are the text fields searched and their boosts
+var defaultSearchFields = map[string]float64{
+	"name":        3,
+	"description": 2,
+	"author":      1,
+	"categories":  1,
+}
+
+// scoreFields are the numeric fields that may be blended into relevance
+var scoreFields = map[string]bool{
+	"popularity_score":     true,
+	"trending_score":       true,
+	"quality_score":        true,
+	"rating_weighted":      true,
+	"install_count":        true,
+	"active_install_count": true,
+}
+
+// ValidateRanking checks that ranking parameters only use known fields and
+// sort orders
+func ValidateRanking(params model.RankingParams) error {
+	for field, boost := range params.FieldBoosts {
+		if _, ok := defaultSearchFields[field]; !ok {
+			return fmt.Errorf("unknown search field: %s", field)
+		}
+		if boost <= 0 {
+			return fmt.Errorf("boost of %s must be positive", field)
+		}
+	}
+
+	for field, factor := range params.ScoreBoosts {
+		if !scoreFields[field] {
+			return fmt.Errorf("unknown score field: %s", field)
+		}
+		if factor <= 0 {
+			return fmt.Errorf("score boost of %s must be positive", field)
+		}
+	}
+
+	switch params.Sort {
+	case "", SortRelevance, SortPopularity, SortTrending, SortRating, SortRecent:
+	default:
+		return fmt.Errorf("unknown sort: %s", params.Sort)
+	}
+
+	return nil
+}
+
+// Order returns the order results are sorted in: the requested sort, or
+// when none is requested, the sort of the query's ranking
+func (q SearchQuery) Order() string {
+	if (q.Sort == "" || q.Sort == SortRelevance) && q.Ranking != nil && q.Ranking.Sort != "" {
+		return q.Ranking.Sort
+	}
+	if q.Sort == "" {
+		return SortRelevance
+	}
+	return q.Sort
+}
+
+// searchFields returns the multi_match fields with their boosts, e.g. name^3
+func searchFields(ranking *model.RankingParams) []string {
+	boosts := defaultSearchFields
+	if ranking != nil && len(ranking.FieldBoosts) > 0 {
+		boosts = ranking.FieldBoosts
+	}
+
+	fields := make([]string, 0, len(boosts))
+	for field, boost := range boosts {
+		if boost == 1 {
+			fields = append(fields, field)
+		} else {
+			fields = append(fields, fmt.Sprintf("%s^%g", field, boost))
+		}
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+// scoreFunctions builds function_score functions adding factor * log(1 + field)
+func scoreFunctions(boosts map[string]float64) []interface{} {
+	fields := make([]string, 0, len(boosts))
+	for field := range boosts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	functions := make([]interface{}, len(fields))
+	for i, field := range fields {
+		functions[i] = map[string]interface{}{
+			"field_value_factor": map[string]interface{}{
+				"field":    field,
+				"factor":   boosts[field],
+				"modifier": "log1p",
+				"missing":  0,
+			},
+		}
+	}
+
+	return functions
+}
+
+// sortClauses returns the Elasticsearch sort for an order, or nil to sort
+// by relevance
+func sortClauses(order string) []interface{} {
+	switch order {
+	case SortPopularity:
+		return []interface{}{
+			map[string]interface{}{"popularity_score": map[string]interface{}{"order": "desc"}},
+		}
+	case SortTrending:
+		return []interface{}{
+			map[string]interface{}{"trending_score": map[string]interface{}{"order": "desc"}},
+		}
+	case SortRating:
+		return []interface{}{
+			map[string]interface{}{"rating_weighted": map[string]interface{}{"order": "desc"}},
+			map[string]interface{}{"rating_count": map[string]interface{}{"order": "desc"}},
+		}
+	case SortRecent:
+		return []interface{}{
+			map[string]interface{}{"last_updated": map[string]interface{}{"order": "desc"}},
+		}
+	default:
+		return nil
+	}
+}
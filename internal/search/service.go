@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -88,8 +89,13 @@ const (
 				"indexed_at": { "type": "date" },
 				"last_updated": { "type": "date" },
 				"install_count": { "type": "long" },
+				"active_install_count": { "type": "long" },
 				"rating_average": { "type": "float" },
 				"rating_count": { "type": "long" },
+				"rating_weighted": { "type": "float" },
+				"tool_call_count": { "type": "long" },
+				"prompt_use_count": { "type": "long" },
+				"template_use_count": { "type": "long" },
 				"popularity_score": { "type": "float" },
 				"trending_score": { "type": "float" },
 				"quality_score": { "type": "float" }
@@ -98,6 +104,9 @@ const (
 	}`
 )
 
+// ErrServerNotFound is returned when a server isn't in the index
+var ErrServerNotFound = errors.New("server not found")
+
 // Service handles Elasticsearch operations
 type Service struct {
 	client *elasticsearch.Client
@@ -164,6 +173,37 @@ func (s *Service) initializeIndex() error {
 		log.Printf("Created index: %s", serverIndexName)
 	} else {
 		log.Printf("Index already exists: %s", serverIndexName)
+
+		// Add any fields introduced since the index was created
+		if err := s.updateMapping(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateMapping applies the current field mappings to an existing index.
+// Elasticsearch only accepts additive changes here, which is all we make.
+func (s *Service) updateMapping() error {
+	var mapping struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(serverMapping), &mapping); err != nil {
+		return fmt.Errorf("failed to parse index mapping: %w", err)
+	}
+
+	res, err := s.client.Indices.PutMapping(
+		[]string{serverIndexName},
+		bytes.NewReader(mapping.Mappings),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update index mapping: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("failed to update index mapping: %s", res.String())
 	}
 
 	return nil
@@ -198,6 +238,35 @@ func (s *Service) IndexServer(ctx context.Context, server *model.ServerDetail) e
 	return nil
 }
 
+// UpdateServerFields partially updates a server document with the given fields
+func (s *Service) UpdateServerFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"doc": fields})
+	if err != nil {
+		return fmt.Errorf("failed to marshal update: %w", err)
+	}
+
+	req := esapi.UpdateRequest{
+		Index:      serverIndexName,
+		DocumentID: id,
+		Body:       bytes.NewReader(body),
+	}
+
+	res, err := req.Do(ctx, s.client)
+	if err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 404 {
+			return fmt.Errorf("%w: %s", ErrServerNotFound, id)
+		}
+		return fmt.Errorf("failed to update document: %s", res.String())
+	}
+
+	return nil
+}
+
 // GetServer retrieves a server by ID
 func (s *Service) GetServer(ctx context.Context, id string) (*model.ServerDetail, error) {
 	req := esapi.GetRequest{
@@ -213,7 +282,7 @@ func (s *Service) GetServer(ctx context.Context, id string) (*model.ServerDetail
 
 	if res.IsError() {
 		if res.StatusCode == 404 {
-			return nil, fmt.Errorf("server not found: %s", id)
+			return nil, fmt.Errorf("%w: %s", ErrServerNotFound, id)
 		}
 		return nil, fmt.Errorf("failed to get document: %s", res.String())
 	}
@@ -230,6 +299,54 @@ func (s *Service) GetServer(ctx context.Context, id string) (*model.ServerDetail
 	return &result.Source, nil
 }
 
+// GetServers retrieves multiple servers by ID, preserving the requested order.
+// IDs that aren't in the index are skipped.
+func (s *Service) GetServers(ctx context.Context, ids []string) ([]model.ServerDetail, error) {
+	if len(ids) == 0 {
+		return []model.ServerDetail{}, nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"ids": ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ids: %w", err)
+	}
+
+	res, err := s.client.Mget(
+		bytes.NewReader(body),
+		s.client.Mget.WithContext(ctx),
+		s.client.Mget.WithIndex(serverIndexName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("failed to get documents: %s", res.String())
+	}
+
+	// Parse response
+	var result struct {
+		Docs []struct {
+			Found  bool               `json:"found"`
+			Source model.ServerDetail `json:"_source"`
+		} `json:"docs"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	servers := make([]model.ServerDetail, 0, len(result.Docs))
+	for _, doc := range result.Docs {
+		if doc.Found {
+			servers = append(servers, doc.Source)
+		}
+	}
+
+	return servers, nil
+}
+
 // DeleteServer deletes a server from the index
 func (s *Service) DeleteServer(ctx context.Context, id string) error {
 	req := esapi.DeleteRequest{
@@ -337,7 +454,7 @@ func (s *Service) buildESQuery(query SearchQuery) map[string]interface{} {
 		boolQuery["must"] = append(boolQuery["must"].([]interface{}), map[string]interface{}{
 			"multi_match": map[string]interface{}{
 				"query":  query.Query,
-				"fields": []string{"name^3", "description^2", "author", "categories"},
+				"fields": searchFields(query.Ranking),
 				"type":   "best_fields",
 			},
 		})
@@ -377,8 +494,19 @@ func (s *Service) buildESQuery(query SearchQuery) map[string]interface{} {
 		}
 	}
 
+	// Exclude servers, e.g. the ones the user already installed
+	if len(query.ExcludeIDs) > 0 {
+		boolQuery["must_not"] = []interface{}{
+			map[string]interface{}{
+				"ids": map[string]interface{}{
+					"values": query.ExcludeIDs,
+				},
+			},
+		}
+	}
+
 	// Set query
-	if len(boolQuery["must"].([]interface{})) > 0 || len(boolQuery["filter"].([]interface{})) > 0 {
+	if len(boolQuery["must"].([]interface{})) > 0 || len(boolQuery["filter"].([]interface{})) > 0 || len(query.ExcludeIDs) > 0 {
 		esQuery["query"] = map[string]interface{}{
 			"bool": boolQuery,
 		}
@@ -388,26 +516,22 @@ func (s *Service) buildESQuery(query SearchQuery) map[string]interface{} {
 		}
 	}
 
-	// Add sorting
-	switch query.Sort {
-	case "popularity":
-		esQuery["sort"] = []interface{}{
-			map[string]interface{}{"popularity_score": map[string]interface{}{"order": "desc"}},
-		}
-	case "trending":
-		esQuery["sort"] = []interface{}{
-			map[string]interface{}{"trending_score": map[string]interface{}{"order": "desc"}},
-		}
-	case "rating":
-		esQuery["sort"] = []interface{}{
-			map[string]interface{}{"rating_average": map[string]interface{}{"order": "desc"}},
-		}
-	case "recent":
-		esQuery["sort"] = []interface{}{
-			map[string]interface{}{"last_updated": map[string]interface{}{"order": "desc"}},
+	// Blend numeric signals into relevance
+	if query.Query != "" && query.Ranking != nil && len(query.Ranking.ScoreBoosts) > 0 {
+		esQuery["query"] = map[string]interface{}{
+			"function_score": map[string]interface{}{
+				"query":      esQuery["query"],
+				"functions":  scoreFunctions(query.Ranking.ScoreBoosts),
+				"score_mode": "sum",
+				"boost_mode": "sum",
+			},
 		}
-	default:
-		// Default to relevance (no explicit sort)
+	}
+
+	// Add sorting; an experiment may change the order used when the
+	// client asks for none
+	if clauses := sortClauses(query.Order()); clauses != nil {
+		esQuery["sort"] = clauses
 	}
 
 	// Add aggregations for facets
@@ -611,18 +735,23 @@ func (s *Service) parseFacets(aggs map[string]interface{}) []Facet {
 
 // SearchQuery represents search parameters
 type SearchQuery struct {
-	Query   string                 `json:"query"`
-	Filters map[string]interface{} `json:"filters"`
-	Sort    string                 `json:"sort"`
-	Offset  int                    `json:"offset"`
-	Limit   int                    `json:"limit"`
+	Query      string                 `json:"query"`
+	Filters    map[string]interface{} `json:"filters"`
+	Sort       string                 `json:"sort"`
+	Offset     int                    `json:"offset"`
+	Limit      int                    `json:"limit"`
+	ExcludeIDs []string               `json:"exclude_ids,omitempty"`
+	Ranking    *model.RankingParams   `json:"ranking,omitempty"`
 }
 
 // SearchResult represents search results
 type SearchResult struct {
-	Total   int                  `json:"total"`
-	Servers []model.ServerDetail `json:"servers"`
-	Facets  []Facet             `json:"facets"`
+	SearchID     string                      `json:"search_id,omitempty"`
+	Total        int                         `json:"total"`
+	Servers      []model.ServerDetail        `json:"servers"`
+	Facets       []Facet                     `json:"facets"`
+	Personalized bool                        `json:"personalized"`
+	Experiment   *model.ExperimentAssignment `json:"experiment,omitempty"`
 }
 
 // Facet represents a search facet
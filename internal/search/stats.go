@@ -0,0 +1,278 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pluggedin/mcp-analytics/internal/model"
+)
+
+// Bucket limits for statistics aggregations
+const (
+	statsTermsSize    = 100
+	statsTopServers   = 3
+	statsToolNameSize = 50
+)
+
+// IndexStats holds server counts aggregated over the index
+type IndexStats struct {
+	TotalServers  int64
+	NewServers    int64
+	ByCategory    map[string]int64
+	ByPackageType map[string]int64
+	ByTransport   map[string]int64
+	BySource      map[string]int64
+}
+
+// termsAgg is the decoded form of a terms aggregation
+type termsAgg struct {
+	Buckets []struct {
+		Key      string `json:"key"`
+		DocCount int64  `json:"doc_count"`
+		Servers  struct {
+			DocCount int64 `json:"doc_count"`
+		} `json:"servers"`
+	} `json:"buckets"`
+}
+
+// IndexStats counts servers overall, since the given time, and by category,
+// package type, transport and source
+func (s *Service) IndexStats(ctx context.Context, since time.Time) (*IndexStats, error) {
+	esQuery := map[string]interface{}{
+		"size":             0,
+		"track_total_hits": true,
+		"aggs": map[string]interface{}{
+			"categories": termsAggQuery("categories", statsTermsSize),
+			"sources":    termsAggQuery("source", statsTermsSize),
+			"new_servers": map[string]interface{}{
+				"filter": map[string]interface{}{
+					"range": map[string]interface{}{"indexed_at": map[string]interface{}{"gte": since}},
+				},
+			},
+			"package_types": nestedServerTerms("packages", "packages.type", statsTermsSize),
+			"transports":    nestedServerTerms("remotes", "remotes.transport", statsTermsSize),
+		},
+	}
+
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+		Aggregations struct {
+			Categories termsAgg `json:"categories"`
+			Sources    termsAgg `json:"sources"`
+			NewServers struct {
+				DocCount int64 `json:"doc_count"`
+			} `json:"new_servers"`
+			PackageTypes struct {
+				Types termsAgg `json:"types"`
+			} `json:"package_types"`
+			Transports struct {
+				Types termsAgg `json:"types"`
+			} `json:"transports"`
+		} `json:"aggregations"`
+	}
+
+	if err := s.aggregate(ctx, esQuery, &result); err != nil {
+		return nil, err
+	}
+
+	aggs := result.Aggregations
+	return &IndexStats{
+		TotalServers:  result.Hits.Total.Value,
+		NewServers:    aggs.NewServers.DocCount,
+		ByCategory:    termCounts(aggs.Categories, false),
+		ByPackageType: termCounts(aggs.PackageTypes.Types, true),
+		ByTransport:   termCounts(aggs.Transports.Types, true),
+		BySource:      termCounts(aggs.Sources, false),
+	}, nil
+}
+
+// CategoryStats summarizes each category's servers, installs and ratings
+func (s *Service) CategoryStats(ctx context.Context, since time.Time) ([]model.CategoryStats, error) {
+	esQuery := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			"categories": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "categories", "size": statsTermsSize},
+				"aggs": map[string]interface{}{
+					"installs":        map[string]interface{}{"sum": map[string]interface{}{"field": "install_count"}},
+					"active_installs": map[string]interface{}{"sum": map[string]interface{}{"field": "active_install_count"}},
+					"new_servers": map[string]interface{}{
+						"filter": map[string]interface{}{
+							"range": map[string]interface{}{"indexed_at": map[string]interface{}{"gte": since}},
+						},
+					},
+					"rated": map[string]interface{}{
+						"filter": map[string]interface{}{
+							"range": map[string]interface{}{"rating_count": map[string]interface{}{"gt": 0}},
+						},
+						"aggs": map[string]interface{}{
+							"rating": map[string]interface{}{"avg": map[string]interface{}{"field": "rating_weighted"}},
+						},
+					},
+					"top": map[string]interface{}{
+						"top_hits": map[string]interface{}{
+							"size":    statsTopServers,
+							"sort":    []interface{}{map[string]interface{}{"trending_score": map[string]interface{}{"order": "desc"}}},
+							"_source": []string{"id", "name", "trending_score"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var result struct {
+		Aggregations struct {
+			Categories struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int64  `json:"doc_count"`
+					Installs struct {
+						Value float64 `json:"value"`
+					} `json:"installs"`
+					ActiveInstalls struct {
+						Value float64 `json:"value"`
+					} `json:"active_installs"`
+					NewServers struct {
+						DocCount int64 `json:"doc_count"`
+					} `json:"new_servers"`
+					Rated struct {
+						Rating struct {
+							Value *float64 `json:"value"`
+						} `json:"rating"`
+					} `json:"rated"`
+					Top struct {
+						Hits struct {
+							Hits []struct {
+								Source model.StatsServer `json:"_source"`
+							} `json:"hits"`
+						} `json:"hits"`
+					} `json:"top"`
+				} `json:"buckets"`
+			} `json:"categories"`
+		} `json:"aggregations"`
+	}
+
+	if err := s.aggregate(ctx, esQuery, &result); err != nil {
+		return nil, err
+	}
+
+	categories := make([]model.CategoryStats, 0, len(result.Aggregations.Categories.Buckets))
+	for _, bucket := range result.Aggregations.Categories.Buckets {
+		stats := model.CategoryStats{
+			Category:       bucket.Key,
+			Servers:        bucket.DocCount,
+			NewServers:     bucket.NewServers.DocCount,
+			TotalInstalls:  int64(bucket.Installs.Value),
+			ActiveInstalls: int64(bucket.ActiveInstalls.Value),
+			TopServers:     make([]model.StatsServer, 0, len(bucket.Top.Hits.Hits)),
+		}
+		if bucket.Rated.Rating.Value != nil {
+			stats.AverageRating = *bucket.Rated.Rating.Value
+		}
+		for _, hit := range bucket.Top.Hits.Hits {
+			stats.TopServers = append(stats.TopServers, hit.Source)
+		}
+		categories = append(categories, stats)
+	}
+
+	return categories, nil
+}
+
+// ToolDeclarations returns the tool names declared by the most servers
+func (s *Service) ToolDeclarations(ctx context.Context) ([]model.ToolDeclaration, error) {
+	esQuery := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			"tools": nestedServerTerms("tools", "tools.name", statsToolNameSize),
+		},
+	}
+
+	var result struct {
+		Aggregations struct {
+			Tools struct {
+				Types termsAgg `json:"types"`
+			} `json:"tools"`
+		} `json:"aggregations"`
+	}
+
+	if err := s.aggregate(ctx, esQuery, &result); err != nil {
+		return nil, err
+	}
+
+	tools := make([]model.ToolDeclaration, 0, len(result.Aggregations.Tools.Types.Buckets))
+	for _, bucket := range result.Aggregations.Tools.Types.Buckets {
+		tools = append(tools, model.ToolDeclaration{Name: bucket.Key, Servers: bucket.Servers.DocCount})
+	}
+
+	return tools, nil
+}
+
+// aggregate runs an aggregation-only query and decodes the response into dest
+func (s *Service) aggregate(ctx context.Context, esQuery map[string]interface{}, dest interface{}) error {
+	body, err := json.Marshal(esQuery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	res, err := s.client.Search(
+		s.client.Search.WithContext(ctx),
+		s.client.Search.WithIndex(serverIndexName),
+		s.client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to execute aggregation: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("aggregation error: %s", res.String())
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// termsAggQuery builds a terms aggregation
+func termsAggQuery(field string, size int) map[string]interface{} {
+	return map[string]interface{}{
+		"terms": map[string]interface{}{"field": field, "size": size},
+	}
+}
+
+// nestedServerTerms builds a terms aggregation over a nested field whose
+// buckets count servers rather than nested documents
+func nestedServerTerms(path, field string, size int) map[string]interface{} {
+	terms := termsAggQuery(field, size)
+	terms["aggs"] = map[string]interface{}{
+		"servers": map[string]interface{}{"reverse_nested": map[string]interface{}{}},
+	}
+
+	return map[string]interface{}{
+		"nested": map[string]interface{}{"path": path},
+		"aggs":   map[string]interface{}{"types": terms},
+	}
+}
+
+// termCounts converts terms buckets to a map, counting servers for nested terms
+func termCounts(agg termsAgg, nested bool) map[string]int64 {
+	counts := make(map[string]int64, len(agg.Buckets))
+	for _, bucket := range agg.Buckets {
+		if nested {
+			counts[bucket.Key] = bucket.Servers.DocCount
+		} else {
+			counts[bucket.Key] = bucket.DocCount
+		}
+	}
+	return counts
+}